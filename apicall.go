@@ -0,0 +1,107 @@
+package cloudcostexplorer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/invzhi/timex"
+)
+
+// ErrAPICallBudgetExhausted is returned by clouds when a billable API call is refused because the daily budget was
+// exhausted.
+var ErrAPICallBudgetExhausted = errors.New("daily API call budget exhausted")
+
+// CloudCallStats is an optional interface for [Cloud] implementations which count their billable API calls.
+type CloudCallStats interface {
+	// CallStats returns the billable API calls statistics of the current day.
+	CallStats() APICallStats
+}
+
+// APICallStats is the count of billable API calls of a day.
+type APICallStats struct {
+	Day    timex.Date
+	Count  int64
+	Budget int64 // daily budget, 0 means unlimited.
+}
+
+// Exhausted returns whether the daily budget was exhausted.
+func (s APICallStats) Exhausted() bool {
+	return s.Budget > 0 && s.Count >= s.Budget
+}
+
+// APICallCounter counts the billable API calls made using a context, like the ones made to render a page.
+type APICallCounter struct {
+	count atomic.Int64
+}
+
+// Count returns the number of calls.
+func (c *APICallCounter) Count() int64 {
+	return c.count.Load()
+}
+
+type apiCallCounterKey struct{}
+
+// ContextWithAPICallCounter returns a context which counts the billable API calls made with it in the counter.
+func ContextWithAPICallCounter(ctx context.Context, counter *APICallCounter) context.Context {
+	return context.WithValue(ctx, apiCallCounterKey{}, counter)
+}
+
+// APICallCounterFromContext returns the counter set with [ContextWithAPICallCounter], or nil if not set.
+func APICallCounterFromContext(ctx context.Context) *APICallCounter {
+	counter, _ := ctx.Value(apiCallCounterKey{}).(*APICallCounter)
+	return counter
+}
+
+// CountAPICall adds a billable API call to the counter of the context, if any.
+func CountAPICall(ctx context.Context) {
+	if counter := APICallCounterFromContext(ctx); counter != nil {
+		counter.count.Add(1)
+	}
+}
+
+// DailyAPICallBudget counts the billable API calls of each UTC day, refusing them after the budget is exhausted.
+type DailyAPICallBudget struct {
+	mu    sync.Mutex
+	stats APICallStats
+}
+
+// NewDailyAPICallBudget creates a [DailyAPICallBudget]. A budget of 0 means unlimited.
+func NewDailyAPICallBudget(budget int64) *DailyAPICallBudget {
+	return &DailyAPICallBudget{
+		stats: APICallStats{
+			Day:    timex.Today(time.UTC),
+			Budget: budget,
+		},
+	}
+}
+
+// Use counts an API call, returning [ErrAPICallBudgetExhausted] without counting it if the budget was exhausted.
+func (b *DailyAPICallBudget) Use() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkDay()
+	if b.stats.Exhausted() {
+		return ErrAPICallBudgetExhausted
+	}
+	b.stats.Count++
+	return nil
+}
+
+// Stats returns the statistics of the current day.
+func (b *DailyAPICallBudget) Stats() APICallStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkDay()
+	return b.stats
+}
+
+// checkDay resets the count when the day changes.
+func (b *DailyAPICallBudget) checkDay() {
+	if today := timex.Today(time.UTC); !today.Equal(b.stats.Day) {
+		b.stats.Day = today
+		b.stats.Count = 0
+	}
+}
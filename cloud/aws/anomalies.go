@@ -0,0 +1,198 @@
+package aws
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/invzhi/timex"
+)
+
+// AnomalyQuery is the query for the cost anomaly detection results.
+type AnomalyQuery struct {
+	Start, End timex.Date                // inclusive period.
+	MonitorArn string                    // if set, only anomalies of this monitor.
+	Feedback   types.AnomalyFeedbackType // if set, only anomalies with this feedback.
+}
+
+// AnomalyMonitor is a cost anomaly detection monitor.
+type AnomalyMonitor struct {
+	Arn       string
+	Name      string
+	Type      types.MonitorType
+	Dimension types.MonitorDimension
+}
+
+// Anomaly is a cost anomaly detected by a monitor.
+type Anomaly struct {
+	ID               string
+	MonitorArn       string
+	MonitorName      string
+	DimensionValue   string
+	Start            string // YYYY-MM-DD
+	End              string // YYYY-MM-DD, blank if the anomaly is still ongoing.
+	TotalImpact      float64
+	ImpactPercentage float64
+	MaxImpact        float64
+	ActualSpend      float64
+	ExpectedSpend    float64
+	MaxScore         float64
+	Feedback         types.AnomalyFeedbackType // blank if no feedback was given.
+	RootCauses       []AnomalyRootCause
+}
+
+// AnomalyRootCause is a root cause of an anomaly. Any of the fields may be blank.
+type AnomalyRootCause struct {
+	LinkedAccount     string
+	LinkedAccountName string
+	Service           string
+	Region            string
+	UsageType         string
+}
+
+// Filters returns the root cause values keyed by the cost explorer parameter ID, only for non-blank values.
+func (r AnomalyRootCause) Filters() map[string]string {
+	ret := map[string]string{}
+	for id, value := range map[string]string{
+		"LINKED_ACCOUNT": r.LinkedAccount,
+		"SERVICE":        r.Service,
+		"REGION":         r.Region,
+		"USAGE_TYPE":     r.UsageType,
+	} {
+		if value != "" {
+			ret[id] = value
+		}
+	}
+	return ret
+}
+
+// AnomalyReport are the cost anomaly detection monitors and anomalies. Errors of each API are returned separately.
+type AnomalyReport struct {
+	MonitorsErr  error
+	Monitors     []*AnomalyMonitor
+	AnomaliesErr error
+	Anomalies    []*Anomaly
+}
+
+// Anomalies returns the anomaly monitors and the anomalies detected in the period, sorted by total impact.
+func (c *Cloud) Anomalies(ctx context.Context, query AnomalyQuery) *AnomalyReport {
+	ret := &AnomalyReport{}
+	ret.Monitors, ret.MonitorsErr = c.anomalyMonitors(ctx)
+	ret.Anomalies, ret.AnomaliesErr = c.anomalies(ctx, query)
+
+	monitorNames := map[string]string{}
+	for _, monitor := range ret.Monitors {
+		monitorNames[monitor.Arn] = monitor.Name
+	}
+	for _, anomaly := range ret.Anomalies {
+		anomaly.MonitorName = monitorNames[anomaly.MonitorArn]
+	}
+
+	return ret
+}
+
+// DetectedAnomalies returns only the anomalies detected in the period, sorted by total impact. The monitor names are
+// not set, so the monitors are not requested.
+func (c *Cloud) DetectedAnomalies(ctx context.Context, query AnomalyQuery) ([]*Anomaly, error) {
+	return c.anomalies(ctx, query)
+}
+
+func (c *Cloud) anomalyMonitors(ctx context.Context) ([]*AnomalyMonitor, error) {
+	var ret []*AnomalyMonitor
+
+	for data, err := range awsAPIIteratorInput(ctx, &costexplorer.GetAnomalyMonitorsInput{},
+		func(ctx context.Context, input *costexplorer.GetAnomalyMonitorsInput) (*costexplorer.GetAnomalyMonitorsOutput, error) {
+			return c.costExplorerClient.GetAnomalyMonitors(ctx, input)
+		}) {
+		if err != nil {
+			return nil, fmt.Errorf("error getting anomaly monitors: %w", err)
+		}
+
+		for _, monitor := range data.AnomalyMonitors {
+			ret = append(ret, &AnomalyMonitor{
+				Arn:       aws.ToString(monitor.MonitorArn),
+				Name:      aws.ToString(monitor.MonitorName),
+				Type:      monitor.MonitorType,
+				Dimension: monitor.MonitorDimension,
+			})
+		}
+	}
+
+	slices.SortFunc(ret, func(a, b *AnomalyMonitor) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return ret, nil
+}
+
+func (c *Cloud) anomalies(ctx context.Context, query AnomalyQuery) ([]*Anomaly, error) {
+	var ret []*Anomaly
+
+	input := &costexplorer.GetAnomaliesInput{
+		DateInterval: &types.AnomalyDateInterval{
+			StartDate: aws.String(query.Start.String()),
+			EndDate:   aws.String(query.End.String()),
+		},
+		Feedback: query.Feedback,
+	}
+	if query.MonitorArn != "" {
+		input.MonitorArn = aws.String(query.MonitorArn)
+	}
+
+	for data, err := range awsAPIIteratorInput(ctx, input,
+		func(ctx context.Context, input *costexplorer.GetAnomaliesInput) (*costexplorer.GetAnomaliesOutput, error) {
+			return c.costExplorerClient.GetAnomalies(ctx, input)
+		}) {
+		if err != nil {
+			return nil, fmt.Errorf("error getting anomalies: %w", err)
+		}
+
+		for _, anomaly := range data.Anomalies {
+			item := &Anomaly{
+				ID:             aws.ToString(anomaly.AnomalyId),
+				MonitorArn:     aws.ToString(anomaly.MonitorArn),
+				DimensionValue: aws.ToString(anomaly.DimensionValue),
+				Start:          anomalyDate(anomaly.AnomalyStartDate),
+				End:            anomalyDate(anomaly.AnomalyEndDate),
+				Feedback:       anomaly.Feedback,
+			}
+			if anomaly.Impact != nil {
+				item.TotalImpact = anomaly.Impact.TotalImpact
+				item.MaxImpact = anomaly.Impact.MaxImpact
+				item.ImpactPercentage = aws.ToFloat64(anomaly.Impact.TotalImpactPercentage)
+				item.ActualSpend = aws.ToFloat64(anomaly.Impact.TotalActualSpend)
+				item.ExpectedSpend = aws.ToFloat64(anomaly.Impact.TotalExpectedSpend)
+			}
+			if anomaly.AnomalyScore != nil {
+				item.MaxScore = anomaly.AnomalyScore.MaxScore
+			}
+			for _, rootCause := range anomaly.RootCauses {
+				item.RootCauses = append(item.RootCauses, AnomalyRootCause{
+					LinkedAccount:     aws.ToString(rootCause.LinkedAccount),
+					LinkedAccountName: aws.ToString(rootCause.LinkedAccountName),
+					Service:           aws.ToString(rootCause.Service),
+					Region:            aws.ToString(rootCause.Region),
+					UsageType:         aws.ToString(rootCause.UsageType),
+				})
+			}
+			ret = append(ret, item)
+		}
+	}
+
+	slices.SortStableFunc(ret, func(a, b *Anomaly) int {
+		return cmp.Compare(b.TotalImpact, a.TotalImpact)
+	})
+	return ret, nil
+}
+
+// anomalyDate returns the date part of the anomaly timestamps, which may be returned with the time.
+func anomalyDate(value *string) string {
+	ret := aws.ToString(value)
+	if len(ret) > 10 {
+		return ret[:10]
+	}
+	return ret
+}
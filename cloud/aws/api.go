@@ -2,14 +2,18 @@ package aws
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"iter"
+	"log/slog"
 	"slices"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
-	"github.com/davecgh/go-spew/spew"
+	"github.com/rrgmc/cloudcostexplorer"
 )
 
 type costAndUsageIterResult struct {
@@ -20,22 +24,38 @@ type costAndUsageIterResult struct {
 type costAndUsageIter iter.Seq2[costAndUsageIterResult, error]
 
 // costAndUsage calls the AWS cost and usage API with the passed filters and returns an iterator.
-func costAndUsage(ctx context.Context, costexplorerClient *costexplorer.Client, costmetric string, start, end string,
+func costAndUsage(ctx context.Context, logger *slog.Logger, queryInfoCallback func(cloudcostexplorer.QueryInfo),
+	costexplorerClient *costexplorer.Client, costmetric string, start, end string, granularity types.Granularity,
 	filters *types.Expression, groupBy []types.GroupDefinition) costAndUsageIter {
 	return func(yield func(costAndUsageIterResult, error) bool) {
-		for data, err := range awsAPIIteratorInput(ctx,
-			&costexplorer.GetCostAndUsageInput{
-				Granularity: types.GranularityDaily,
-				Metrics: []string{
-					costmetric,
-				},
-				Filter: filters,
-				TimePeriod: &types.DateInterval{
-					Start: aws.String(start),
-					End:   aws.String(end),
-				},
-				GroupBy: groupBy,
+		startTime := time.Now()
+		var pages int
+		defer func() {
+			logger.DebugContext(ctx, "cost and usage API finished",
+				"pages", pages, "duration", time.Since(startTime))
+		}()
+
+		input := &costexplorer.GetCostAndUsageInput{
+			Granularity: granularity,
+			Metrics: []string{
+				costmetric,
 			},
+			Filter: filters,
+			TimePeriod: &types.DateInterval{
+				Start: aws.String(start),
+				End:   aws.String(end),
+			},
+			GroupBy: groupBy,
+		}
+		if queryInfoCallback != nil {
+			// encode before the iterator sets the page token.
+			queryInfo := newAPIQueryInfo("GetCostAndUsage", input)
+			defer func() {
+				queryInfoCallback(queryInfo.withStats(pages, time.Since(startTime)))
+			}()
+		}
+
+		for data, err := range awsAPIIteratorInput(ctx, input,
 			func(ctx context.Context, input *costexplorer.GetCostAndUsageInput) (*costexplorer.GetCostAndUsageOutput, error) {
 				return costexplorerClient.GetCostAndUsage(ctx, input)
 			}) {
@@ -43,6 +63,7 @@ func costAndUsage(ctx context.Context, costexplorerClient *costexplorer.Client,
 				yield(costAndUsageIterResult{}, err)
 				return
 			}
+			pages++
 			if len(data.DimensionValueAttributes) > 0 {
 				hasLinkedAccount := false
 				for _, gd := range data.GroupDefinitions {
@@ -51,7 +72,8 @@ func costAndUsage(ctx context.Context, costexplorerClient *costexplorer.Client,
 					}
 				}
 				if !hasLinkedAccount {
-					fmt.Printf("DimensionValueAttributes: %s\n", spew.Sdump(data.DimensionValueAttributes))
+					logger.DebugContext(ctx, "cost and usage dimension value attributes",
+						"attributes", data.DimensionValueAttributes)
 				}
 			}
 
@@ -69,23 +91,64 @@ func costAndUsage(ctx context.Context, costexplorerClient *costexplorer.Client,
 	}
 }
 
+// apiQueryInfo is the debug information of an API call, with the input encoded as JSON.
+type apiQueryInfo cloudcostexplorer.QueryInfo
+
+func newAPIQueryInfo(title string, input any) apiQueryInfo {
+	query, err := json.MarshalIndent(input, "", "  ")
+	if err != nil {
+		query = []byte(fmt.Sprintf("error encoding input: %s", err))
+	}
+	return apiQueryInfo{
+		Title: title,
+		Query: string(query),
+	}
+}
+
+// withStats returns the query info with the page count and latency.
+func (q apiQueryInfo) withStats(pages int, latency time.Duration) cloudcostexplorer.QueryInfo {
+	ret := cloudcostexplorer.QueryInfo(q)
+	ret.Stats = []cloudcostexplorer.QueryInfoValue{
+		{Name: "Pages", Value: strconv.Itoa(pages)},
+		{Name: "Latency", Value: latency.Round(time.Millisecond).String()},
+	}
+	return ret
+}
+
 // costAndUsageWithResources calls the AWS cost and usage with resources API with the passed filters and returns an iterator.
-func costAndUsageWithResources(ctx context.Context, costexplorerClient *costexplorer.Client, costmetric string, start, end string,
-	filters *types.Expression, groupBy []types.GroupDefinition) costAndUsageIter {
+func costAndUsageWithResources(ctx context.Context, logger *slog.Logger,
+	queryInfoCallback func(cloudcostexplorer.QueryInfo), costexplorerClient *costexplorer.Client, costmetric string,
+	start, end string, granularity types.Granularity, filters *types.Expression,
+	groupBy []types.GroupDefinition) costAndUsageIter {
 	return func(yield func(costAndUsageIterResult, error) bool) {
-		for data, err := range awsAPIIteratorInput(ctx,
-			&costexplorer.GetCostAndUsageWithResourcesInput{
-				Granularity: types.GranularityDaily,
-				Metrics: []string{
-					costmetric,
-				},
-				Filter: filters,
-				TimePeriod: &types.DateInterval{
-					Start: aws.String(start),
-					End:   aws.String(end),
-				},
-				GroupBy: groupBy,
+		startTime := time.Now()
+		var pages int
+		defer func() {
+			logger.DebugContext(ctx, "cost and usage with resources API finished",
+				"pages", pages, "duration", time.Since(startTime))
+		}()
+
+		input := &costexplorer.GetCostAndUsageWithResourcesInput{
+			Granularity: granularity,
+			Metrics: []string{
+				costmetric,
+			},
+			Filter: filters,
+			TimePeriod: &types.DateInterval{
+				Start: aws.String(start),
+				End:   aws.String(end),
 			},
+			GroupBy: groupBy,
+		}
+		if queryInfoCallback != nil {
+			// encode before the iterator sets the page token.
+			queryInfo := newAPIQueryInfo("GetCostAndUsageWithResources", input)
+			defer func() {
+				queryInfoCallback(queryInfo.withStats(pages, time.Since(startTime)))
+			}()
+		}
+
+		for data, err := range awsAPIIteratorInput(ctx, input,
 			func(ctx context.Context, input *costexplorer.GetCostAndUsageWithResourcesInput) (*costexplorer.GetCostAndUsageWithResourcesOutput, error) {
 				return costexplorerClient.GetCostAndUsageWithResources(ctx, input)
 			}) {
@@ -93,6 +156,7 @@ func costAndUsageWithResources(ctx context.Context, costexplorerClient *costexpl
 				yield(costAndUsageIterResult{}, err)
 				return
 			}
+			pages++
 
 			for _, group := range data.ResultsByTime {
 				for _, v := range group.Groups {
@@ -195,6 +259,65 @@ func tagsWithValues(ctx context.Context, costexplorerClient *costexplorer.Client
 	}
 }
 
+// costCategories returns the cost category names, or the values of a cost category if costCategoryName is set,
+// based on a filter.
+func costCategories(ctx context.Context, costexplorerClient *costexplorer.Client,
+	start, end string, filters *types.Expression, costCategoryName *string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for data, err := range awsAPIIteratorInput(ctx, &costexplorer.GetCostCategoriesInput{
+			Filter: filters,
+			TimePeriod: &types.DateInterval{
+				Start: aws.String(start),
+				End:   aws.String(end),
+			},
+			CostCategoryName: costCategoryName,
+		}, func(ctx context.Context, input *costexplorer.GetCostCategoriesInput) (*costexplorer.GetCostCategoriesOutput, error) {
+			return costexplorerClient.GetCostCategories(ctx, input)
+		}) {
+			if err != nil {
+				yield("", err)
+				return
+			}
+
+			values := data.CostCategoryNames
+			if costCategoryName != nil {
+				values = data.CostCategoryValues
+			}
+			for _, value := range values {
+				if !yield(value, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// costCategoriesWithValues returns a list of cost categories and their possible values based on a filter.
+func costCategoriesWithValues(ctx context.Context, costexplorerClient *costexplorer.Client,
+	start, end string, filters *types.Expression) iter.Seq2[tagValue, error] {
+	return func(yield func(tagValue, error) bool) {
+		for categoryName, err := range costCategories(ctx, costexplorerClient, start, end, filters, nil) {
+			if err != nil {
+				yield(tagValue{}, fmt.Errorf("couldn't fetch cost category data: %w", err))
+				return
+			}
+			curCategory := tagValue{
+				Name: categoryName,
+			}
+			for cv, err := range costCategories(ctx, costexplorerClient, start, end, filters, &categoryName) {
+				if err != nil {
+					yield(tagValue{}, fmt.Errorf("couldn't fetch cost category '%s' values: %w", categoryName, err))
+					return
+				}
+				curCategory.Values = append(curCategory.Values, cv)
+			}
+			if !yield(curCategory, nil) {
+				return
+			}
+		}
+	}
+}
+
 type tagValueItem struct {
 	value tagValue
 	err   error
@@ -203,13 +326,24 @@ type tagValueItem struct {
 // tagsWithValuesFuture returns tagsWithValues as a channel.
 func tagsWithValuesFuture(ctx context.Context, costexplorerClient *costexplorer.Client,
 	start, end string, filters *types.Expression) chan tagValueItem {
+	return tagValuesFuture(ctx, tagsWithValues(ctx, costexplorerClient, start, end, filters))
+}
+
+// costCategoriesWithValuesFuture returns costCategoriesWithValues as a channel.
+func costCategoriesWithValuesFuture(ctx context.Context, costexplorerClient *costexplorer.Client,
+	start, end string, filters *types.Expression) chan tagValueItem {
+	return tagValuesFuture(ctx, costCategoriesWithValues(ctx, costexplorerClient, start, end, filters))
+}
+
+// tagValuesFuture returns a tag values iterator as a channel.
+func tagValuesFuture(ctx context.Context, values iter.Seq2[tagValue, error]) chan tagValueItem {
 	c := make(chan tagValueItem, 100)
 	go func() {
 		defer close(c)
-		for tag, err := range tagsWithValues(ctx, costexplorerClient, start, end, filters) {
+		for tag, err := range values {
 			if err != nil {
 				select {
-				case c <- tagValueItem{err: fmt.Errorf("couldn't fetch tag data: %w", err)}:
+				case c <- tagValueItem{err: err}:
 				case <-ctx.Done():
 				}
 				return
@@ -256,3 +390,52 @@ func usageTagGroupsFuture(ctx context.Context, costexplorerClient *costexplorer.
 	}()
 	return c
 }
+
+type rightsizingItem struct {
+	value types.RightsizingRecommendation
+	err   error
+}
+
+// rightsizingRecommendationsFuture returns the EC2 rightsizing recommendations of the accounts as a channel. If no
+// accounts are passed, returns the recommendations of all accounts.
+func rightsizingRecommendationsFuture(ctx context.Context, costexplorerClient *costexplorer.Client,
+	accounts []string) chan rightsizingItem {
+	c := make(chan rightsizingItem, 100)
+	go func() {
+		defer close(c)
+		input := &costexplorer.GetRightsizingRecommendationInput{
+			Service: aws.String("AmazonEC2"),
+			Configuration: &types.RightsizingRecommendationConfiguration{
+				BenefitsConsidered:   true,
+				RecommendationTarget: types.RecommendationTargetSameInstanceFamily,
+			},
+		}
+		if len(accounts) > 0 {
+			input.Filter = &types.Expression{
+				Dimensions: &types.DimensionValues{
+					Key:    types.DimensionLinkedAccount,
+					Values: accounts,
+				},
+			}
+		}
+		for data, err := range awsAPIIteratorInput(ctx, input, func(ctx context.Context, input *costexplorer.GetRightsizingRecommendationInput) (*costexplorer.GetRightsizingRecommendationOutput, error) {
+			return costexplorerClient.GetRightsizingRecommendation(ctx, input)
+		}) {
+			if err != nil {
+				select {
+				case c <- rightsizingItem{err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			for _, recommendation := range data.RightsizingRecommendations {
+				select {
+				case c <- rightsizingItem{value: recommendation}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return c
+}
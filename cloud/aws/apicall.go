@@ -0,0 +1,32 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/smithy-go/middleware"
+	"github.com/rrgmc/cloudcostexplorer"
+)
+
+var _ cloudcostexplorer.CloudCallStats = (*Cloud)(nil)
+
+// CallStats returns the cost explorer API calls of the current day. Each cost explorer API request is billed.
+func (c *Cloud) CallStats() cloudcostexplorer.APICallStats {
+	return c.callBudget.Stats()
+}
+
+// addCallCounter adds a middleware to the cost explorer client which counts each operation in the daily budget and
+// in the context counter, refusing it if the daily budget was exhausted. Retries are not counted again, as they run
+// after the initialize step.
+func (c *Cloud) addCallCounter(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("CallCounter",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+			middleware.InitializeOutput, middleware.Metadata, error) {
+			if err := c.callBudget.Use(); err != nil {
+				c.logger.WarnContext(ctx, "API call refused", "operation", middleware.GetOperationName(ctx),
+					"error", err)
+				return middleware.InitializeOutput{}, middleware.Metadata{}, err
+			}
+			cloudcostexplorer.CountAPICall(ctx)
+			return next.HandleInitialize(ctx, in)
+		}), middleware.Before)
+}
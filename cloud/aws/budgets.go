@@ -0,0 +1,192 @@
+package aws
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/budgets"
+	budgettypes "github.com/aws/aws-sdk-go-v2/service/budgets/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/rrgmc/cloudcostexplorer"
+)
+
+// Budget is an AWS budget with its actual and forecasted spend in the current budget period.
+type Budget struct {
+	Name     string
+	Type     budgettypes.BudgetType
+	TimeUnit budgettypes.TimeUnit
+	Unit     string // unit of the amounts, usually USD for cost budgets.
+	Limit    float64
+	Actual   float64
+	Forecast float64
+
+	Filters            []cloudcostexplorer.QueryFilter // budget cost filters as cost explorer filters.
+	UnsupportedFilters []string                        // budget cost filters which have no cost explorer equivalent.
+}
+
+// ActualPercentage returns the percentage of the limit which was already spent.
+func (b *Budget) ActualPercentage() float64 {
+	if b.Limit == 0 {
+		return 0
+	}
+	return b.Actual * 100 / b.Limit
+}
+
+// ForecastPercentage returns the percentage of the limit which is forecasted to be spent.
+func (b *Budget) ForecastPercentage() float64 {
+	if b.Limit == 0 {
+		return 0
+	}
+	return b.Forecast * 100 / b.Limit
+}
+
+// budgetFilterParameters maps the budget cost filter names to the cost explorer parameter IDs.
+var budgetFilterParameters = map[string]string{
+	"AZ":             "AZ",
+	"InstanceType":   "INSTANCE_TYPE",
+	"LinkedAccount":  "LINKED_ACCOUNT",
+	"Operation":      "OPERATION",
+	"PurchaseType":   "PURCHASE_TYPE",
+	"RecordType":     "RECORD_TYPE",
+	"Region":         "REGION",
+	"Service":        "SERVICE",
+	"UsageType":      "USAGE_TYPE",
+	"UsageTypeGroup": "USAGE_TYPE_GROUP",
+	"TagKeyValue":    "TAG",
+	"CostCategory":   "COST_CATEGORY",
+}
+
+// Budgets returns the budgets of the account, sorted by the forecasted percentage of the limit.
+func (c *Cloud) Budgets(ctx context.Context) ([]*Budget, error) {
+	identity, err := c.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting account ID: %w", err)
+	}
+
+	var ret []*Budget
+	filterExpressions := map[string]bool{}
+
+	for data, err := range awsAPIIteratorInput(ctx, &budgets.DescribeBudgetsInput{
+		AccountId: identity.Account,
+	}, func(ctx context.Context, input *budgets.DescribeBudgetsInput) (*budgets.DescribeBudgetsOutput, error) {
+		return c.budgetsClient.DescribeBudgets(ctx, input,
+			budgets.WithAPIOptions(addBudgetFilterExpressions(filterExpressions)))
+	}) {
+		if err != nil {
+			return nil, fmt.Errorf("error getting budgets: %w", err)
+		}
+
+		for _, budget := range data.Budgets {
+			item := &Budget{
+				Name:     aws.ToString(budget.BudgetName),
+				Type:     budget.BudgetType,
+				TimeUnit: budget.TimeUnit,
+			}
+			amounts := map[*float64]*string{}
+			if budget.BudgetLimit != nil {
+				item.Unit = aws.ToString(budget.BudgetLimit.Unit)
+				amounts[&item.Limit] = budget.BudgetLimit.Amount
+			}
+			if budget.CalculatedSpend != nil {
+				if budget.CalculatedSpend.ActualSpend != nil {
+					amounts[&item.Actual] = budget.CalculatedSpend.ActualSpend.Amount
+				}
+				if budget.CalculatedSpend.ForecastedSpend != nil {
+					amounts[&item.Forecast] = budget.CalculatedSpend.ForecastedSpend.Amount
+				}
+			}
+			if err := parseAmounts(amounts); err != nil {
+				return nil, err
+			}
+			item.Filters, item.UnsupportedFilters = budgetFilters(budget.CostFilters)
+			if filterExpressions[item.Name] {
+				item.UnsupportedFilters = append(item.UnsupportedFilters, "FilterExpression")
+			}
+			ret = append(ret, item)
+		}
+	}
+
+	slices.SortStableFunc(ret, func(a, b *Budget) int {
+		return cmp.Compare(b.ForecastPercentage(), a.ForecastPercentage())
+	})
+	return ret, nil
+}
+
+// budgetFilters converts the budget cost filters to cost explorer filters. Cost explorer filters support only a
+// single value for each parameter, so filters with multiple values are returned as unsupported.
+func budgetFilters(costFilters map[string][]string) ([]cloudcostexplorer.QueryFilter, []string) {
+	var filters []cloudcostexplorer.QueryFilter
+	var unsupported []string
+	for _, name := range slices.Sorted(maps.Keys(costFilters)) {
+		values := costFilters[name]
+		parameterID, ok := budgetFilterParameters[name]
+		if !ok || len(values) != 1 {
+			unsupported = append(unsupported, name)
+			continue
+		}
+		value := values[0]
+		switch parameterID {
+		case "TAG":
+			// "user:Key$Value"
+			value = strings.Replace(strings.TrimPrefix(value, "user:"), "$", cloudcostexplorer.DataSeparator, 1)
+		case "COST_CATEGORY":
+			// "Name$Value"
+			value = strings.Replace(value, "$", cloudcostexplorer.DataSeparator, 1)
+		}
+		filters = append(filters, cloudcostexplorer.QueryFilter{
+			ID:    parameterID,
+			Value: value,
+		})
+	}
+	return filters, unsupported
+}
+
+// addBudgetFilterExpressions adds a middleware which sets the names of the budgets that use a filter expression
+// instead of the deprecated cost filters. The SDK version in use doesn't return the filter expression, so it is read
+// from the raw response, to avoid showing these budgets as unfiltered.
+func addBudgetFilterExpressions(names map[string]bool) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("BudgetFilterExpressions",
+			func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (
+				middleware.DeserializeOutput, middleware.Metadata, error) {
+				out, metadata, err := next.HandleDeserialize(ctx, in)
+				if err != nil {
+					return out, metadata, err
+				}
+				response, ok := out.RawResponse.(*smithyhttp.Response)
+				if !ok || response.StatusCode < 200 || response.StatusCode >= 300 {
+					return out, metadata, err
+				}
+				body, err := io.ReadAll(response.Body)
+				if err != nil {
+					return out, metadata, fmt.Errorf("error reading budgets response: %w", err)
+				}
+				response.Body = io.NopCloser(bytes.NewReader(body))
+
+				var data struct {
+					Budgets []struct {
+						BudgetName       string
+						FilterExpression json.RawMessage
+					}
+				}
+				if err := json.Unmarshal(body, &data); err == nil {
+					for _, budget := range data.Budgets {
+						if len(budget.FilterExpression) > 0 && string(budget.FilterExpression) != "null" {
+							names[budget.BudgetName] = true
+						}
+					}
+				}
+				return out, metadata, nil
+			}), middleware.After)
+	}
+}
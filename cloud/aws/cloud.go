@@ -2,34 +2,65 @@ package aws
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/budgets"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
 	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
 	"github.com/rrgmc/cloudcostexplorer"
 )
 
 type Cloud struct {
 	cfg                *aws.Config
 	costExplorerClient *costexplorer.Client
+	budgetsClient      *budgets.Client
+	stsClient          *sts.Client
+	orgsClient         *organizations.Client
+	logger             *slog.Logger
 
-	parameters     cloudcostexplorer.Parameters
-	linkedAccounts map[string]string
+	orgRefreshInterval time.Duration
+	dailyCallBudget    int64
+	callBudget         *cloudcostexplorer.DailyAPICallBudget
+
+	parameters cloudcostexplorer.Parameters
+	orgMutex   sync.RWMutex
+	org        *organization
 }
 
 var _ cloudcostexplorer.Cloud = (*Cloud)(nil)
 
 func New(ctx context.Context, options ...CloudOption) (*Cloud, error) {
-	ret := &Cloud{}
+	ret := &Cloud{
+		logger: slog.Default(),
+	}
 	for _, opt := range options {
 		opt(ret)
 	}
-	ret.load(ctx)
+	ret.callBudget = cloudcostexplorer.NewDailyAPICallBudget(ret.dailyCallBudget)
 	if ret.cfg == nil {
-		ret.costExplorerClient = costexplorer.New(costexplorer.Options{})
+		ret.costExplorerClient = costexplorer.New(costexplorer.Options{
+			APIOptions: []func(*middleware.Stack) error{ret.addCallCounter},
+		})
+		ret.budgetsClient = budgets.New(budgets.Options{})
+		ret.stsClient = sts.New(sts.Options{})
+		ret.orgsClient = organizations.New(organizations.Options{})
 	} else {
-		ret.costExplorerClient = costexplorer.NewFromConfig(*ret.cfg)
+		ret.costExplorerClient = costexplorer.NewFromConfig(*ret.cfg, func(o *costexplorer.Options) {
+			o.APIOptions = append(o.APIOptions, ret.addCallCounter)
+		})
+		ret.budgetsClient = budgets.NewFromConfig(*ret.cfg)
+		ret.stsClient = sts.NewFromConfig(*ret.cfg)
+		ret.orgsClient = organizations.NewFromConfig(*ret.cfg)
+	}
+	ret.load(ctx)
+	if ret.orgRefreshInterval > 0 {
+		go ret.refreshOrganization(ctx, ret.orgRefreshInterval)
 	}
 	return ret, nil
 }
@@ -47,12 +78,19 @@ func (c *Cloud) Parameters() cloudcostexplorer.Parameters {
 }
 
 func (c *Cloud) ParameterTitle(id string, defaultValue string) string {
-	if id != "LINKED_ACCOUNT" {
-		return defaultValue
-	}
-
-	if la, ok := c.linkedAccounts[defaultValue]; ok {
-		return la
+	switch id {
+	case "LINKED_ACCOUNT":
+		if la, ok := c.accountName(defaultValue); ok {
+			return la
+		}
+	case "OU":
+		if ou, ok := c.unitName(defaultValue); ok {
+			return ou
+		}
+	case "ACCOUNT_TAG":
+		if _, tv, ok := strings.Cut(defaultValue, cloudcostexplorer.DataSeparator); ok {
+			return tv
+		}
 	}
 
 	return defaultValue
@@ -199,31 +237,36 @@ func (c *Cloud) load(ctx context.Context) {
 			HasData:       true,
 			DataRequired:  true,
 		},
+		{
+			ID:            "COST_CATEGORY",
+			Name:          "Cost category",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+			HasData:       true,
+			DataRequired:  true,
+		},
+		{
+			// computed from the linked account, the data is the OU level, where 1 is the top-level OU.
+			ID:            "OU",
+			Name:          "Organizational unit",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+			HasData:       true,
+		},
+		{
+			// computed from the linked account, the data is the account tag key.
+			ID:            "ACCOUNT_TAG",
+			Name:          "Account tag",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+			HasData:       true,
+			DataRequired:  true,
+		},
 	}
 
-	c.loadLinkedAccounts(ctx)
-}
-
-func (c *Cloud) loadLinkedAccounts(ctx context.Context) {
-	var orgsClient *organizations.Client
-	if c.cfg != nil {
-		orgsClient = organizations.NewFromConfig(*c.cfg)
-	} else {
-		orgsClient = organizations.New(organizations.Options{})
-	}
-
-	c.linkedAccounts = map[string]string{}
-
-	for pageOrgs, err := range awsAPIIteratorInput(ctx, &organizations.ListAccountsInput{}, func(ctx context.Context, input *organizations.ListAccountsInput) (*organizations.ListAccountsOutput, error) {
-		return orgsClient.ListAccounts(ctx, input)
-	}) {
-		if err != nil {
-			fmt.Printf("error listing accounts: %v\n", err)
-			break
-		}
-
-		for _, org := range pageOrgs.Accounts {
-			c.linkedAccounts[*org.Id] = *org.Name
-		}
-	}
+	c.org = &organization{}
+	c.loadOrganization(ctx)
 }
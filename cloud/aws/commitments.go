@@ -0,0 +1,409 @@
+package aws
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/invzhi/timex"
+)
+
+// CommitmentDimension is a dimension used to drill down Savings Plans and Reserved Instances utilization and
+// coverage. Not all dimensions are supported by all APIs.
+type CommitmentDimension string
+
+const (
+	CommitmentDimensionService        CommitmentDimension = "SERVICE"
+	CommitmentDimensionInstanceFamily CommitmentDimension = "INSTANCE_FAMILY"
+	CommitmentDimensionRegion         CommitmentDimension = "REGION"
+)
+
+// CommitmentDimensions is the list of all commitment dimensions.
+var CommitmentDimensions = []CommitmentDimension{
+	CommitmentDimensionService,
+	CommitmentDimensionInstanceFamily,
+	CommitmentDimensionRegion,
+}
+
+func (d CommitmentDimension) Title() string {
+	switch d {
+	case CommitmentDimensionService:
+		return "Service"
+	case CommitmentDimensionInstanceFamily:
+		return "Instance family"
+	case CommitmentDimensionRegion:
+		return "Region"
+	default:
+		return string(d)
+	}
+}
+
+// commitmentAPI maps the commitment dimensions to the dimensions supported by each API.
+type commitmentAPI struct {
+	filters map[CommitmentDimension]types.Dimension
+	groups  map[CommitmentDimension]string
+}
+
+var (
+	savingsPlansUtilizationAPI = commitmentAPI{
+		filters: map[CommitmentDimension]types.Dimension{
+			CommitmentDimensionInstanceFamily: types.DimensionInstanceTypeFamily,
+			CommitmentDimensionRegion:         types.DimensionRegion,
+		},
+	}
+	savingsPlansCoverageAPI = commitmentAPI{
+		filters: map[CommitmentDimension]types.Dimension{
+			CommitmentDimensionService:        types.DimensionService,
+			CommitmentDimensionInstanceFamily: types.Dimension("INSTANCE_FAMILY"),
+			CommitmentDimensionRegion:         types.DimensionRegion,
+		},
+		groups: map[CommitmentDimension]string{
+			CommitmentDimensionService:        "SERVICE",
+			CommitmentDimensionInstanceFamily: "INSTANCE_FAMILY",
+			CommitmentDimensionRegion:         "REGION",
+		},
+	}
+	reservationUtilizationAPI = commitmentAPI{
+		filters: map[CommitmentDimension]types.Dimension{
+			CommitmentDimensionService: types.DimensionService,
+			CommitmentDimensionRegion:  types.DimensionRegion,
+		},
+	}
+	reservationCoverageAPI = commitmentAPI{
+		filters: map[CommitmentDimension]types.Dimension{
+			CommitmentDimensionService: types.DimensionService,
+			CommitmentDimensionRegion:  types.DimensionRegion,
+		},
+		groups: map[CommitmentDimension]string{
+			// reservation coverage can't be grouped by INSTANCE_TYPE_FAMILY, the instance types are summed by family
+			// after the query.
+			CommitmentDimensionInstanceFamily: "INSTANCE_TYPE",
+			CommitmentDimensionRegion:         "REGION",
+		},
+	}
+)
+
+// filter returns the API filter expression, and the list of filters not supported by the API, which are ignored.
+func (a commitmentAPI) filter(filters map[CommitmentDimension]string) (*types.Expression, []CommitmentDimension) {
+	var expressions []types.Expression
+	var unsupported []CommitmentDimension
+	for _, dim := range CommitmentDimensions {
+		value, ok := filters[dim]
+		if !ok {
+			continue
+		}
+		apiDim, ok := a.filters[dim]
+		if !ok {
+			unsupported = append(unsupported, dim)
+			continue
+		}
+		expressions = append(expressions, types.Expression{
+			Dimensions: &types.DimensionValues{
+				Key:    apiDim,
+				Values: []string{value},
+			},
+		})
+	}
+	return buildCostExplorerFilter(expressions), unsupported
+}
+
+// groupBy returns the API group definition, or nil if the grouping is not supported by the API.
+func (a commitmentAPI) groupBy(dim CommitmentDimension) []types.GroupDefinition {
+	apiDim, ok := a.groups[dim]
+	if !ok {
+		return nil
+	}
+	return []types.GroupDefinition{
+		{
+			Key:  aws.String(apiDim),
+			Type: types.GroupDefinitionTypeDimension,
+		},
+	}
+}
+
+// CommitmentQuery is the query for the commitments report.
+type CommitmentQuery struct {
+	Start, End timex.Date                     // inclusive period.
+	GroupBy    CommitmentDimension            // dimension to group the coverage by.
+	Filters    map[CommitmentDimension]string // dimension values to filter by.
+}
+
+// CommitmentUtilization is the utilization of the purchased commitments.
+// For Savings Plans the values are in dollars, for Reserved Instances in hours.
+type CommitmentUtilization struct {
+	Err                   error
+	IgnoredFilters        []CommitmentDimension // filters which are not supported by the API.
+	Total                 float64
+	Used                  float64
+	Unused                float64
+	UtilizationPercentage float64
+	NetSavings            float64
+}
+
+// CommitmentCoverage is how much of the eligible usage is covered by commitments.
+// For Savings Plans the values are in dollars, for Reserved Instances in hours.
+type CommitmentCoverage struct {
+	Err            error
+	IgnoredFilters []CommitmentDimension // filters which are not supported by the API.
+	IsGrouped      bool                  // false if the grouping is not supported by the API.
+	Items          []*CommitmentCoverageItem
+	Total          CommitmentCoverageItem
+}
+
+// CommitmentCoverageItem is the coverage of a single group value.
+type CommitmentCoverageItem struct {
+	Key      string
+	Covered  float64
+	OnDemand float64
+	Total    float64
+}
+
+// CoveragePercentage returns the percentage of the total which is covered by commitments.
+func (i CommitmentCoverageItem) CoveragePercentage() float64 {
+	if i.Total == 0 {
+		return 0
+	}
+	return i.Covered * 100 / i.Total
+}
+
+func (i *CommitmentCoverageItem) add(other CommitmentCoverageItem) {
+	i.Covered += other.Covered
+	i.OnDemand += other.OnDemand
+	i.Total += other.Total
+}
+
+// CommitmentReport is the Savings Plans and Reserved Instances utilization and coverage report.
+type CommitmentReport struct {
+	SavingsPlansUtilization CommitmentUtilization
+	SavingsPlansCoverage    CommitmentCoverage
+	ReservationUtilization  CommitmentUtilization
+	ReservationCoverage     CommitmentCoverage
+}
+
+// Commitments returns the Savings Plans and Reserved Instances utilization and coverage for the period. Errors of
+// each API are returned in each section, as accounts without any commitment return errors for some of them.
+func (c *Cloud) Commitments(ctx context.Context, query CommitmentQuery) *CommitmentReport {
+	period := &types.DateInterval{
+		Start: aws.String(query.Start.String()),
+		// end time is exclusive in cost explorer, must use next day
+		End: aws.String(query.End.AddDays(1).String()),
+	}
+
+	return &CommitmentReport{
+		SavingsPlansUtilization: c.savingsPlansUtilization(ctx, period, query),
+		SavingsPlansCoverage:    c.savingsPlansCoverage(ctx, period, query),
+		ReservationUtilization:  c.reservationUtilization(ctx, period, query),
+		ReservationCoverage:     c.reservationCoverage(ctx, period, query),
+	}
+}
+
+func (c *Cloud) savingsPlansUtilization(ctx context.Context, period *types.DateInterval, query CommitmentQuery) CommitmentUtilization {
+	var ret CommitmentUtilization
+	var filter *types.Expression
+	filter, ret.IgnoredFilters = savingsPlansUtilizationAPI.filter(query.Filters)
+
+	data, err := c.costExplorerClient.GetSavingsPlansUtilization(ctx, &costexplorer.GetSavingsPlansUtilizationInput{
+		TimePeriod:  period,
+		Filter:      filter,
+		Granularity: types.GranularityMonthly,
+	})
+	if err != nil {
+		ret.Err = fmt.Errorf("error getting savings plans utilization: %w", err)
+		return ret
+	}
+	if data.Total == nil || data.Total.Utilization == nil {
+		return ret
+	}
+
+	ret.Err = parseAmounts(map[*float64]*string{
+		&ret.Total:                 data.Total.Utilization.TotalCommitment,
+		&ret.Used:                  data.Total.Utilization.UsedCommitment,
+		&ret.Unused:                data.Total.Utilization.UnusedCommitment,
+		&ret.UtilizationPercentage: data.Total.Utilization.UtilizationPercentage,
+	})
+	if ret.Err == nil && data.Total.Savings != nil {
+		ret.Err = parseAmounts(map[*float64]*string{
+			&ret.NetSavings: data.Total.Savings.NetSavings,
+		})
+	}
+	return ret
+}
+
+func (c *Cloud) savingsPlansCoverage(ctx context.Context, period *types.DateInterval, query CommitmentQuery) CommitmentCoverage {
+	var ret CommitmentCoverage
+	var filter *types.Expression
+	filter, ret.IgnoredFilters = savingsPlansCoverageAPI.filter(query.Filters)
+	groupBy := savingsPlansCoverageAPI.groupBy(query.GroupBy)
+	ret.IsGrouped = groupBy != nil
+
+	items := map[string]*CommitmentCoverageItem{}
+
+	for data, err := range awsAPIIteratorInput(ctx, &costexplorer.GetSavingsPlansCoverageInput{
+		TimePeriod:  period,
+		Filter:      filter,
+		Granularity: types.GranularityMonthly,
+		GroupBy:     groupBy,
+	}, func(ctx context.Context, input *costexplorer.GetSavingsPlansCoverageInput) (*costexplorer.GetSavingsPlansCoverageOutput, error) {
+		return c.costExplorerClient.GetSavingsPlansCoverage(ctx, input)
+	}) {
+		if err != nil {
+			ret.Err = fmt.Errorf("error getting savings plans coverage: %w", err)
+			return ret
+		}
+
+		for _, coverage := range data.SavingsPlansCoverages {
+			if coverage.Coverage == nil {
+				continue
+			}
+			var item CommitmentCoverageItem
+			err := parseAmounts(map[*float64]*string{
+				&item.Covered:  coverage.Coverage.SpendCoveredBySavingsPlans,
+				&item.OnDemand: coverage.Coverage.OnDemandCost,
+				&item.Total:    coverage.Coverage.TotalCost,
+			})
+			if err != nil {
+				ret.Err = err
+				return ret
+			}
+			addCoverageItem(items, attributesKey(coverage.Attributes), item)
+			ret.Total.add(item)
+		}
+	}
+
+	ret.Items = sortedCoverageItems(items)
+	return ret
+}
+
+func (c *Cloud) reservationUtilization(ctx context.Context, period *types.DateInterval, query CommitmentQuery) CommitmentUtilization {
+	var ret CommitmentUtilization
+	var filter *types.Expression
+	filter, ret.IgnoredFilters = reservationUtilizationAPI.filter(query.Filters)
+
+	data, err := c.costExplorerClient.GetReservationUtilization(ctx, &costexplorer.GetReservationUtilizationInput{
+		TimePeriod:  period,
+		Filter:      filter,
+		Granularity: types.GranularityMonthly,
+	})
+	if err != nil {
+		ret.Err = fmt.Errorf("error getting reservation utilization: %w", err)
+		return ret
+	}
+	if data.Total == nil {
+		return ret
+	}
+
+	ret.Err = parseAmounts(map[*float64]*string{
+		&ret.Total:                 data.Total.PurchasedHours,
+		&ret.Used:                  data.Total.TotalActualHours,
+		&ret.Unused:                data.Total.UnusedHours,
+		&ret.UtilizationPercentage: data.Total.UtilizationPercentage,
+		&ret.NetSavings:            data.Total.NetRISavings,
+	})
+	return ret
+}
+
+func (c *Cloud) reservationCoverage(ctx context.Context, period *types.DateInterval, query CommitmentQuery) CommitmentCoverage {
+	var ret CommitmentCoverage
+	var filter *types.Expression
+	filter, ret.IgnoredFilters = reservationCoverageAPI.filter(query.Filters)
+	groupBy := reservationCoverageAPI.groupBy(query.GroupBy)
+	ret.IsGrouped = groupBy != nil
+
+	items := map[string]*CommitmentCoverageItem{}
+
+	for data, err := range awsAPIIteratorInput(ctx, &costexplorer.GetReservationCoverageInput{
+		TimePeriod:  period,
+		Filter:      filter,
+		Granularity: types.GranularityMonthly,
+		GroupBy:     groupBy,
+	}, func(ctx context.Context, input *costexplorer.GetReservationCoverageInput) (*costexplorer.GetReservationCoverageOutput, error) {
+		return c.costExplorerClient.GetReservationCoverage(ctx, input)
+	}) {
+		if err != nil {
+			ret.Err = fmt.Errorf("error getting reservation coverage: %w", err)
+			return ret
+		}
+
+		for _, byTime := range data.CoveragesByTime {
+			if !ret.IsGrouped {
+				item, err := coverageHoursItem(byTime.Total)
+				if err != nil {
+					ret.Err = err
+					return ret
+				}
+				ret.Total.add(item)
+				continue
+			}
+			for _, group := range byTime.Groups {
+				item, err := coverageHoursItem(group.Coverage)
+				if err != nil {
+					ret.Err = err
+					return ret
+				}
+				key := attributesKey(group.Attributes)
+				if query.GroupBy == CommitmentDimensionInstanceFamily {
+					key = instanceTypeFamily(key)
+				}
+				addCoverageItem(items, key, item)
+				ret.Total.add(item)
+			}
+		}
+	}
+
+	ret.Items = sortedCoverageItems(items)
+	return ret
+}
+
+// instanceTypeFamily returns the family of an instance type, like "m5" for "m5.large" or "db.r6g" for
+// "db.r6g.xlarge".
+func instanceTypeFamily(instanceType string) string {
+	if idx := strings.LastIndex(instanceType, "."); idx > 0 {
+		return instanceType[:idx]
+	}
+	return instanceType
+}
+
+func coverageHoursItem(coverage *types.Coverage) (CommitmentCoverageItem, error) {
+	var item CommitmentCoverageItem
+	if coverage == nil || coverage.CoverageHours == nil {
+		return item, nil
+	}
+	err := parseAmounts(map[*float64]*string{
+		&item.Covered:  coverage.CoverageHours.ReservedHours,
+		&item.OnDemand: coverage.CoverageHours.OnDemandHours,
+		&item.Total:    coverage.CoverageHours.TotalRunningHours,
+	})
+	return item, err
+}
+
+func addCoverageItem(items map[string]*CommitmentCoverageItem, key string, item CommitmentCoverageItem) {
+	cur, ok := items[key]
+	if !ok {
+		cur = &CommitmentCoverageItem{Key: key}
+		items[key] = cur
+	}
+	cur.add(item)
+}
+
+// sortedCoverageItems returns the coverage items sorted by on-demand value, descending, as these are the best
+// candidates for new commitments.
+func sortedCoverageItems(items map[string]*CommitmentCoverageItem) []*CommitmentCoverageItem {
+	return slices.SortedFunc(maps.Values(items), func(a, b *CommitmentCoverageItem) int {
+		return cmp.Compare(b.OnDemand, a.OnDemand)
+	})
+}
+
+// attributesKey returns a key from the attributes of a group, joining all values if there is more than one.
+func attributesKey(attributes map[string]string) string {
+	var values []string
+	for _, key := range slices.Sorted(maps.Keys(attributes)) {
+		values = append(values, attributes[key])
+	}
+	return strings.Join(values, " / ")
+}
@@ -0,0 +1,218 @@
+package aws
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/invzhi/timex"
+	"github.com/rrgmc/cloudcostexplorer"
+)
+
+// comparisonMetric is the cost metric used by the comparison APIs, the same one used by the cost explorer queries.
+const comparisonMetric = "UnblendedCost"
+
+// CostComparisonQuery is the query for the month-over-month cost comparison. Both months are calendar months.
+type CostComparisonQuery struct {
+	BaselineMonth   timex.Date // any day of the baseline month.
+	ComparisonMonth timex.Date // any day of the comparison month.
+	Filters         []cloudcostexplorer.QueryFilter
+}
+
+// CostComparisonValue is a metric value in the baseline and comparison months.
+type CostComparisonValue struct {
+	Baseline   float64
+	Comparison float64
+	Difference float64
+	Unit       string
+}
+
+// CostComparison is the cost comparison of a single selector, like a service.
+type CostComparison struct {
+	Selector map[string]string // cost explorer parameter IDs and values which select this item.
+	CostComparisonValue
+}
+
+// CostDriver is a factor which contributes to the cost difference between the months, like a usage change or
+// discount.
+type CostDriver struct {
+	Selector map[string]string // cost explorer parameter IDs and values which select this driver.
+	Type     string            // like USAGE_CHANGE, SAVINGS_PLAN_USAGE, CREDIT.
+	Name     string
+	CostComparisonValue
+}
+
+// CostComparisonReport is the month-over-month cost comparison by service, and the drivers of the differences,
+// ranked by the absolute difference. Errors of each API are returned separately.
+type CostComparisonReport struct {
+	ComparisonsErr error
+	Total          CostComparisonValue
+	Comparisons    []*CostComparison
+	DriversErr     error
+	Drivers        []*CostDriver
+}
+
+// CostComparison returns the month-over-month cost comparison and the cost comparison drivers.
+func (c *Cloud) CostComparison(ctx context.Context, query CostComparisonQuery) *CostComparisonReport {
+	baseline := monthInterval(query.BaselineMonth)
+	comparison := monthInterval(query.ComparisonMonth)
+
+	ret := &CostComparisonReport{}
+
+	expressions, err := c.filterExpressions(query.Filters)
+	if err != nil {
+		ret.ComparisonsErr, ret.DriversErr = err, err
+		return ret
+	}
+	filter := buildCostExplorerFilter(expressions)
+
+	ret.Total, ret.Comparisons, ret.ComparisonsErr = c.costAndUsageComparisons(ctx, baseline, comparison, filter)
+	ret.Drivers, ret.DriversErr = c.costComparisonDrivers(ctx, baseline, comparison, filter)
+	return ret
+}
+
+func (c *Cloud) costAndUsageComparisons(ctx context.Context, baseline, comparison *types.DateInterval,
+	filter *types.Expression) (CostComparisonValue, []*CostComparison, error) {
+	var total CostComparisonValue
+	var ret []*CostComparison
+
+	for data, err := range awsAPIIteratorInput(ctx, &costexplorer.GetCostAndUsageComparisonsInput{
+		BaselineTimePeriod:   baseline,
+		ComparisonTimePeriod: comparison,
+		MetricForComparison:  aws.String(comparisonMetric),
+		Filter:               filter,
+		GroupBy: []types.GroupDefinition{
+			{
+				Key:  aws.String("SERVICE"),
+				Type: types.GroupDefinitionTypeDimension,
+			},
+		},
+	}, func(ctx context.Context, input *costexplorer.GetCostAndUsageComparisonsInput) (*costexplorer.GetCostAndUsageComparisonsOutput, error) {
+		return c.costExplorerClient.GetCostAndUsageComparisons(ctx, input)
+	}) {
+		if err != nil {
+			return total, nil, fmt.Errorf("error getting cost and usage comparisons: %w", err)
+		}
+
+		if metric, ok := data.TotalCostAndUsage[comparisonMetric]; ok {
+			if total, err = comparisonValue(metric); err != nil {
+				return total, nil, err
+			}
+		}
+
+		for _, item := range data.CostAndUsageComparisons {
+			metric, ok := item.Metrics[comparisonMetric]
+			if !ok {
+				continue
+			}
+			value, err := comparisonValue(metric)
+			if err != nil {
+				return total, nil, err
+			}
+			ret = append(ret, &CostComparison{
+				Selector:            expressionSelector(item.CostAndUsageSelector),
+				CostComparisonValue: value,
+			})
+		}
+	}
+
+	slices.SortStableFunc(ret, func(a, b *CostComparison) int {
+		return cmp.Compare(math.Abs(b.Difference), math.Abs(a.Difference))
+	})
+	return total, ret, nil
+}
+
+func (c *Cloud) costComparisonDrivers(ctx context.Context, baseline, comparison *types.DateInterval,
+	filter *types.Expression) ([]*CostDriver, error) {
+	var ret []*CostDriver
+
+	for data, err := range awsAPIIteratorInput(ctx, &costexplorer.GetCostComparisonDriversInput{
+		BaselineTimePeriod:   baseline,
+		ComparisonTimePeriod: comparison,
+		MetricForComparison:  aws.String(comparisonMetric),
+		Filter:               filter,
+	}, func(ctx context.Context, input *costexplorer.GetCostComparisonDriversInput) (*costexplorer.GetCostComparisonDriversOutput, error) {
+		return c.costExplorerClient.GetCostComparisonDrivers(ctx, input)
+	}) {
+		if err != nil {
+			return nil, fmt.Errorf("error getting cost comparison drivers: %w", err)
+		}
+
+		for _, item := range data.CostComparisonDrivers {
+			selector := expressionSelector(item.CostSelector)
+			for _, driver := range item.CostDrivers {
+				metric, ok := driver.Metrics[comparisonMetric]
+				if !ok {
+					continue
+				}
+				value, err := comparisonValue(metric)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, &CostDriver{
+					Selector:            selector,
+					Type:                aws.ToString(driver.Type),
+					Name:                aws.ToString(driver.Name),
+					CostComparisonValue: value,
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(ret, func(a, b *CostDriver) int {
+		return cmp.Compare(math.Abs(b.Difference), math.Abs(a.Difference))
+	})
+	return ret, nil
+}
+
+// monthInterval returns the interval of the calendar month of the date. The end date is exclusive.
+func monthInterval(date timex.Date) *types.DateInterval {
+	start := timex.MustNewDate(date.Year(), date.Month(), 1)
+	return &types.DateInterval{
+		Start: aws.String(start.String()),
+		End:   aws.String(start.Add(0, 1, 0).String()),
+	}
+}
+
+func comparisonValue(metric types.ComparisonMetricValue) (CostComparisonValue, error) {
+	ret := CostComparisonValue{
+		Unit: aws.ToString(metric.Unit),
+	}
+	err := parseAmounts(map[*float64]*string{
+		&ret.Baseline:   metric.BaselineTimePeriodAmount,
+		&ret.Comparison: metric.ComparisonTimePeriodAmount,
+		&ret.Difference: metric.Difference,
+	})
+	return ret, err
+}
+
+// expressionSelector returns the cost explorer parameter IDs and values of a selector expression, using the first
+// value of each dimension. Tags and cost categories use the same format as the filters.
+func expressionSelector(expression *types.Expression) map[string]string {
+	ret := map[string]string{}
+	var add func(expression *types.Expression)
+	add = func(expression *types.Expression) {
+		switch {
+		case expression == nil:
+		case expression.Dimensions != nil && len(expression.Dimensions.Values) > 0:
+			ret[string(expression.Dimensions.Key)] = expression.Dimensions.Values[0]
+		case expression.Tags != nil && len(expression.Tags.Values) > 0:
+			ret["TAG"] = fmt.Sprintf("%s%s%s", aws.ToString(expression.Tags.Key), cloudcostexplorer.DataSeparator,
+				expression.Tags.Values[0])
+		case expression.CostCategories != nil && len(expression.CostCategories.Values) > 0:
+			ret["COST_CATEGORY"] = fmt.Sprintf("%s%s%s", aws.ToString(expression.CostCategories.Key),
+				cloudcostexplorer.DataSeparator, expression.CostCategories.Values[0])
+		default:
+			for _, and := range expression.And {
+				add(&and)
+			}
+		}
+	}
+	add(expression)
+	return ret
+}
@@ -3,8 +3,11 @@ package aws
 import (
 	"context"
 	"errors"
+	"fmt"
 	"slices"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
 	"github.com/rrgmc/cloudcostexplorer"
 )
 
@@ -30,13 +33,35 @@ func (e *extraDataUsageTypeGroups) merge(other *extraDataUsageTypeGroups) {
 	}
 }
 
+// extraDataTags is a list of tag-like keys and values, like tags ("TAG") or cost categories ("COST_CATEGORY").
 type extraDataTags struct {
-	err  error
-	data map[string]*extraDataTag
+	paramID string
+	err     error
+	data    map[string]*extraDataTag
+}
+
+// newExtraDataTags reads the tag values from the future channel. The name is used in error messages.
+func newExtraDataTags(paramID string, name string, future chan tagValueItem) *extraDataTags {
+	ret := &extraDataTags{
+		paramID: paramID,
+		data:    map[string]*extraDataTag{},
+	}
+	for tt := range future {
+		if tt.err != nil {
+			ret.err = fmt.Errorf("error getting %s: %w", name, tt.err)
+			break
+		}
+		tag := tt.value
+		ret.data[tag.Name] = &extraDataTag{
+			Name:   tag.Name,
+			Values: tag.Values,
+		}
+	}
+	return ret
 }
 
 func (e *extraDataTags) ExtraDataType() string {
-	return "TAG"
+	return e.paramID
 }
 
 func (e *extraDataTags) merge(other *extraDataTags) {
@@ -59,6 +84,49 @@ func (e *extraDataTags) merge(other *extraDataTags) {
 	}
 }
 
+// extraDataRightsizing is a list of EC2 rightsizing recommendations.
+type extraDataRightsizing struct {
+	err  error
+	data []extraDataRightsizingRecommendation
+}
+
+// newExtraDataRightsizing reads the rightsizing recommendations from the future channel.
+func newExtraDataRightsizing(future chan rightsizingItem) *extraDataRightsizing {
+	ret := &extraDataRightsizing{}
+	for item := range future {
+		if item.err != nil {
+			ret.err = fmt.Errorf("error getting rightsizing recommendations: %w", item.err)
+			break
+		}
+		recommendation, err := newExtraDataRightsizingRecommendation(item.value)
+		if err != nil {
+			ret.err = err
+			break
+		}
+		ret.data = append(ret.data, recommendation)
+	}
+	return ret
+}
+
+func (e *extraDataRightsizing) ExtraDataType() string {
+	return "RIGHTSIZING"
+}
+
+func (e *extraDataRightsizing) merge(other *extraDataRightsizing) {
+	if other.err != nil {
+		e.err = errors.Join(e.err, other.err)
+	} else {
+		for _, od := range other.data {
+			if slices.ContainsFunc(e.data, func(d extraDataRightsizingRecommendation) bool {
+				return d.ResourceID == od.ResourceID
+			}) {
+				continue
+			}
+			e.data = append(e.data, od)
+		}
+	}
+}
+
 type extraDataUsageTypeGroup struct {
 	Unit          string
 	Value         string
@@ -70,21 +138,90 @@ type extraDataTag struct {
 	Values []string
 }
 
-// QueryExtraOutput outputs a list of usage type group values and tags available for the current filter.
+type extraDataRightsizingRecommendation struct {
+	AccountID               string
+	ResourceID              string
+	InstanceName            string
+	Region                  string
+	Action                  types.RightsizingType
+	CurrentType             string
+	TargetType              string // blank when the recommendation is to terminate the instance.
+	MonthlyCost             float64
+	EstimatedMonthlySavings float64
+	MaxCPU                  float64
+	MaxMemory               *float64 // only available if memory metrics are collected.
+}
+
+func newExtraDataRightsizingRecommendation(recommendation types.RightsizingRecommendation) (extraDataRightsizingRecommendation, error) {
+	ret := extraDataRightsizingRecommendation{
+		AccountID: aws.ToString(recommendation.AccountId),
+		Action:    recommendation.RightsizingType,
+	}
+	amounts := map[*float64]*string{}
+	if current := recommendation.CurrentInstance; current != nil {
+		ret.ResourceID = aws.ToString(current.ResourceId)
+		ret.InstanceName = aws.ToString(current.InstanceName)
+		amounts[&ret.MonthlyCost] = current.MonthlyCost
+		if current.ResourceDetails != nil && current.ResourceDetails.EC2ResourceDetails != nil {
+			ret.CurrentType = aws.ToString(current.ResourceDetails.EC2ResourceDetails.InstanceType)
+			ret.Region = aws.ToString(current.ResourceDetails.EC2ResourceDetails.Region)
+		}
+		if current.ResourceUtilization != nil && current.ResourceUtilization.EC2ResourceUtilization != nil {
+			utilization := current.ResourceUtilization.EC2ResourceUtilization
+			amounts[&ret.MaxCPU] = utilization.MaxCpuUtilizationPercentage
+			if aws.ToString(utilization.MaxMemoryUtilizationPercentage) != "" {
+				ret.MaxMemory = new(float64)
+				amounts[ret.MaxMemory] = utilization.MaxMemoryUtilizationPercentage
+			}
+		}
+	}
+	switch {
+	case recommendation.ModifyRecommendationDetail != nil:
+		for _, target := range recommendation.ModifyRecommendationDetail.TargetInstances {
+			if !target.DefaultTargetInstance && len(recommendation.ModifyRecommendationDetail.TargetInstances) > 1 {
+				continue
+			}
+			if target.ResourceDetails != nil && target.ResourceDetails.EC2ResourceDetails != nil {
+				ret.TargetType = aws.ToString(target.ResourceDetails.EC2ResourceDetails.InstanceType)
+			}
+			amounts[&ret.EstimatedMonthlySavings] = target.EstimatedMonthlySavings
+			break
+		}
+	case recommendation.TerminateRecommendationDetail != nil:
+		amounts[&ret.EstimatedMonthlySavings] = recommendation.TerminateRecommendationDetail.EstimatedMonthlySavings
+	}
+	return ret, parseAmounts(amounts)
+}
+
+// QueryExtraOutput outputs a list of usage type group values, tags and cost categories available for the current
+// filter, and the EC2 rightsizing recommendations when filtering by EC2.
 func (c *Cloud) QueryExtraOutput(ctx context.Context, extraData []cloudcostexplorer.QueryExtraData) cloudcostexplorer.QueryExtraOutput {
 	out := &extraOutput{}
 
 	var edUsageTypeGroups extraDataUsageTypeGroups
 	edTags := extraDataTags{
-		data: make(map[string]*extraDataTag),
+		paramID: "TAG",
+		data:    make(map[string]*extraDataTag),
 	}
+	edCostCategories := extraDataTags{
+		paramID: "COST_CATEGORY",
+		data:    make(map[string]*extraDataTag),
+	}
+	var edRightsizing extraDataRightsizing
 
 	for _, data := range extraData {
 		switch dt := data.(type) {
 		case *extraDataUsageTypeGroups:
 			edUsageTypeGroups.merge(dt)
 		case *extraDataTags:
-			edTags.merge(dt)
+			switch dt.paramID {
+			case edTags.paramID:
+				edTags.merge(dt)
+			case edCostCategories.paramID:
+				edCostCategories.merge(dt)
+			}
+		case *extraDataRightsizing:
+			edRightsizing.merge(dt)
 		}
 	}
 
@@ -93,7 +230,15 @@ func (c *Cloud) QueryExtraOutput(ctx context.Context, extraData []cloudcostexplo
 	}
 
 	if edTags.err != nil || len(edTags.data) > 0 {
-		out.tags = &extraOutputTags{data: edTags}
+		out.tags = &extraOutputTags{title: "Tags", data: edTags}
+	}
+
+	if edCostCategories.err != nil || len(edCostCategories.data) > 0 {
+		out.costCategories = &extraOutputTags{title: "Cost categories", data: edCostCategories}
+	}
+
+	if edRightsizing.err != nil || len(edRightsizing.data) > 0 {
+		out.rightsizing = &extraOutputRightsizing{data: edRightsizing}
 	}
 
 	return out
@@ -1,17 +1,22 @@
 package aws
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"iter"
+	"slices"
 	"strings"
 
+	"github.com/dustin/go-humanize"
 	"github.com/rrgmc/cloudcostexplorer"
 )
 
 type extraOutput struct {
 	usageTypeGroups *extraOutputUsageTypeGroups
 	tags            *extraOutputTags
+	costCategories  *extraOutputTags
+	rightsizing     *extraOutputRightsizing
 }
 
 func (e extraOutput) Close() {
@@ -23,7 +28,17 @@ func (e extraOutput) ExtraOutputs() iter.Seq2[cloudcostexplorer.ValueOutput, err
 			yield(e.usageTypeGroups, nil)
 		}
 		if e.tags != nil {
-			yield(e.tags, nil)
+			if !yield(e.tags, nil) {
+				return
+			}
+		}
+		if e.costCategories != nil {
+			if !yield(e.costCategories, nil) {
+				return
+			}
+		}
+		if e.rightsizing != nil {
+			yield(e.rightsizing, nil)
 		}
 	}
 }
@@ -69,14 +84,15 @@ func (e extraOutputUsageTypeGroups) Output(ctx context.Context, vctx cloudcostex
 }
 
 type extraOutputTags struct {
-	data extraDataTags
+	title string
+	data  extraDataTags
 }
 
 func (e extraOutputTags) Output(ctx context.Context, vctx cloudcostexplorer.ValueContext, uq *cloudcostexplorer.URLQuery) (string, error) {
 	var sb strings.Builder
 
 	if e.data.err != nil {
-		_, _ = sb.WriteString(`<h3>Tags</h3>`)
+		_, _ = sb.WriteString(fmt.Sprintf(`<h3>%s</h3>`, e.title))
 		_, _ = sb.WriteString(fmt.Sprintf(`<p>error: %s</p>`, e.data.err.Error()))
 		return sb.String(), nil
 	}
@@ -87,15 +103,15 @@ func (e extraOutputTags) Output(ctx context.Context, vctx cloudcostexplorer.Valu
 	for _, tag := range e.data.data {
 		if !isData {
 			isData = true
-			_, _ = sb.WriteString(`<h3>Tags</h3>`)
+			_, _ = sb.WriteString(fmt.Sprintf(`<h3>%s</h3>`, e.title))
 			vctx.Flush()
 
 			_, _ = sb.WriteString(`<table class="table table-striped table-bordered"><tbody>`)
-			_, _ = sb.WriteString(fmt.Sprintf(`<thead><th>Tag</th><th>Values</th></thead><tbody>`))
+			_, _ = sb.WriteString(`<thead><th>Name</th><th>Values</th></thead><tbody>`)
 		}
 
 		_, _ = sb.WriteString(fmt.Sprintf(`<tr><td><a href="%s">%s</a></td><td>`,
-			uq.Clone().Set("group2", fmt.Sprintf("TAG%s%s", cloudcostexplorer.DataSeparator, tag.Name)),
+			uq.Clone().Set("group2", fmt.Sprintf("%s%s%s", e.data.paramID, cloudcostexplorer.DataSeparator, tag.Name)),
 			tag.Name))
 
 		isCollapsed := len(tag.Values) > 10
@@ -115,7 +131,7 @@ func (e extraOutputTags) Output(ctx context.Context, vctx cloudcostexplorer.Valu
 			}
 
 			_, _ = sb.WriteString(fmt.Sprintf(`<li class="list-group-item"><a href="%s">%s</a></li>`+"\n",
-				uq.Clone().Set(vctx.FilterParamName("TAG"), fmt.Sprintf("%s%s%s", tag.Name, cloudcostexplorer.DataSeparator, tagValue)),
+				uq.Clone().Add(vctx.FilterParamName(e.data.paramID), fmt.Sprintf("%s%s%s", tag.Name, cloudcostexplorer.DataSeparator, tagValue)),
 				tv))
 		}
 		_, _ = sb.WriteString(`</ul>`)
@@ -135,3 +151,60 @@ func (e extraOutputTags) Output(ctx context.Context, vctx cloudcostexplorer.Valu
 
 	return sb.String(), nil
 }
+
+type extraOutputRightsizing struct {
+	data extraDataRightsizing
+}
+
+func (e extraOutputRightsizing) Output(ctx context.Context, vctx cloudcostexplorer.ValueContext, uq *cloudcostexplorer.URLQuery) (string, error) {
+	var sb strings.Builder
+
+	_, _ = sb.WriteString(`<h3>Rightsizing recommendations</h3>`)
+
+	if e.data.err != nil {
+		_, _ = sb.WriteString(fmt.Sprintf(`<p>error: %s</p>`, e.data.err.Error()))
+		return sb.String(), nil
+	}
+
+	recommendations := slices.SortedFunc(slices.Values(e.data.data), func(a, b extraDataRightsizingRecommendation) int {
+		return cmp.Compare(b.EstimatedMonthlySavings, a.EstimatedMonthlySavings)
+	})
+
+	formatPct := func(value float64) string {
+		return fmt.Sprintf("%s%%", humanize.CommafWithDigits(value, 2))
+	}
+
+	// resources can only be queried in the last 14 days, without comparison periods.
+	resourceQuery := uq.Clone().Set("period", "d14")
+	for key := range resourceQuery.Params() {
+		if key != "period" && strings.HasPrefix(key, "period") {
+			resourceQuery.Remove(key)
+		}
+	}
+
+	_, _ = sb.WriteString(`<table class="table table-striped table-bordered table-sm">`)
+	_, _ = sb.WriteString(`<thead><th>Instance ID</th><th>Name</th><th>Account</th><th>Region</th><th>Action</th><th>Current type</th><th>Target type</th><th>Monthly cost</th><th>Est. monthly savings</th><th>Max CPU</th><th>Max memory</th></thead><tbody>`)
+	for _, recommendation := range recommendations {
+		maxMemory := "-"
+		if recommendation.MaxMemory != nil {
+			maxMemory = formatPct(*recommendation.MaxMemory)
+		}
+		_, _ = sb.WriteString(`<tr>`)
+		_, _ = sb.WriteString(fmt.Sprintf(`<td><a href="%s">%s</a></td>`,
+			resourceQuery.Clone().Set(vctx.FilterParamName("RESOURCE_ID"), recommendation.ResourceID), recommendation.ResourceID))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td>%s</td>`, recommendation.InstanceName))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td>%s</td>`, recommendation.AccountID))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td>%s</td>`, recommendation.Region))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td>%s</td>`, recommendation.Action))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td>%s</td>`, recommendation.CurrentType))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td>%s</td>`, recommendation.TargetType))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td align="right">%s</td>`, cloudcostexplorer.FormatMoney(recommendation.MonthlyCost)))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td align="right"><strong>%s</strong></td>`, cloudcostexplorer.FormatMoney(recommendation.EstimatedMonthlySavings)))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td align="right">%s</td>`, formatPct(recommendation.MaxCPU)))
+		_, _ = sb.WriteString(fmt.Sprintf(`<td align="right">%s</td>`, maxMemory))
+		_, _ = sb.WriteString(`</tr>`)
+	}
+	_, _ = sb.WriteString(`</tbody></table>`)
+
+	return sb.String(), nil
+}
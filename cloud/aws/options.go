@@ -1,6 +1,11 @@
 package aws
 
-import "github.com/aws/aws-sdk-go-v2/aws"
+import (
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
 
 type CloudOption func(options *Cloud)
 
@@ -10,3 +15,27 @@ func WithCloudConfig(cfg aws.Config) CloudOption {
 		options.cfg = &cfg
 	}
 }
+
+// WithOrganizationRefreshInterval sets the interval to reload the AWS Organizations data (account names, OUs and
+// account tags). The default is to load it only once.
+func WithOrganizationRefreshInterval(interval time.Duration) CloudOption {
+	return func(options *Cloud) {
+		options.orgRefreshInterval = interval
+	}
+}
+
+// WithDailyAPICallBudget sets the maximum number of cost explorer API calls per UTC day, which are billed per request.
+// Calls after the budget is exhausted return [cloudcostexplorer.ErrAPICallBudgetExhausted], and the query extra data
+// is skipped. The default is unlimited.
+func WithDailyAPICallBudget(budget int64) CloudOption {
+	return func(options *Cloud) {
+		options.dailyCallBudget = budget
+	}
+}
+
+// WithLogger sets the logger for diagnostics, like query parameters and durations. The default is [slog.Default].
+func WithLogger(logger *slog.Logger) CloudOption {
+	return func(options *Cloud) {
+		options.logger = logger
+	}
+}
@@ -0,0 +1,404 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/rrgmc/cloudcostexplorer"
+)
+
+// errNoMatchingAccounts is returned when an organization filter doesn't match any account.
+var errNoMatchingAccounts = errors.New("no accounts match the organization filter")
+
+// errOrganizationNotLoaded is returned when an organization filter is used but its organization data could not be
+// loaded.
+var errOrganizationNotLoaded = errors.New("organization data is not loaded")
+
+// organization is the AWS Organizations data used to map linked accounts to OUs and account tags.
+type organization struct {
+	accounts    map[string]*organizationAccount // by account ID.
+	units       map[string]string               // root and OU names by ID.
+	unitsLoaded bool                            // whether the OUs and the account paths were loaded.
+	tagsLoaded  bool                            // whether the account tags were loaded.
+}
+
+// isLoaded returns whether the data needed by the organization parameter was loaded.
+func (o *organization) isLoaded(id string) bool {
+	switch id {
+	case "OU":
+		return o.unitsLoaded
+	case "ACCOUNT_TAG":
+		return o.tagsLoaded
+	default:
+		return false
+	}
+}
+
+// organizationAccount is an account of the organization.
+type organizationAccount struct {
+	Name string
+	Path []string          // root and OU IDs from the root to the account parent.
+	Tags map[string]string // account tags.
+}
+
+// isOrganizationParameter returns whether the parameter is computed from the linked account using the organization
+// data, as the cost explorer can't group or filter by it directly.
+func isOrganizationParameter(id string) bool {
+	return id == "OU" || id == "ACCOUNT_TAG"
+}
+
+// accountName returns the account name, or false if the account is not known.
+func (c *Cloud) accountName(accountID string) (string, bool) {
+	c.orgMutex.RLock()
+	defer c.orgMutex.RUnlock()
+	if account, ok := c.org.accounts[accountID]; ok {
+		return account.Name, true
+	}
+	return "", false
+}
+
+// unitName returns the root or OU name, or false if it is not known.
+func (c *Cloud) unitName(unitID string) (string, bool) {
+	c.orgMutex.RLock()
+	defer c.orgMutex.RUnlock()
+	name, ok := c.org.units[unitID]
+	return name, ok
+}
+
+// organizationKey returns the item key of an organization group for a linked account.
+// For OU, the group data is the OU level, where 1 is the top-level OU. The default is the account parent.
+// For ACCOUNT_TAG, the group data is the tag key.
+// Accounts without an OU at the level or without the tag return a blank key, which can't be used as filter.
+func (c *Cloud) organizationKey(group cloudcostexplorer.QueryGroup, accountID string) cloudcostexplorer.ItemKey {
+	c.orgMutex.RLock()
+	defer c.orgMutex.RUnlock()
+
+	blankKey := cloudcostexplorer.ItemKey{
+		Value: cloudcostexplorer.EmptyValue{},
+	}
+
+	account := c.org.accounts[accountID]
+
+	switch group.ID {
+	case "OU":
+		if account == nil || len(account.Path) == 0 {
+			return blankKey
+		}
+		unitID := account.Path[len(account.Path)-1]
+		if group.Data != "" {
+			level, _ := strconv.Atoi(group.Data)
+			if level >= len(account.Path) {
+				return blankKey
+			}
+			unitID = account.Path[level]
+		}
+		return cloudcostexplorer.ItemKey{
+			ID:    unitID,
+			Value: c.org.units[unitID],
+		}
+	case "ACCOUNT_TAG":
+		var value string
+		if account != nil {
+			value = account.Tags[group.Data]
+		}
+		if value == "" {
+			return blankKey
+		}
+		return cloudcostexplorer.ItemKey{
+			ID:    fmt.Sprintf("%s%s%s", group.Data, cloudcostexplorer.DataSeparator, value),
+			Value: value,
+		}
+	default:
+		return blankKey
+	}
+}
+
+// validateOrganizationGroup checks the group data of the organization groups.
+func validateOrganizationGroup(group cloudcostexplorer.QueryGroup) error {
+	switch group.ID {
+	case "OU":
+		if group.Data != "" {
+			if level, err := strconv.Atoi(group.Data); err != nil || level < 1 {
+				return fmt.Errorf("invalid OU level '%s'", group.Data)
+			}
+		}
+	case "ACCOUNT_TAG":
+		if group.Data == "" {
+			return errors.New("account tag group requires a tag key")
+		}
+	}
+	return nil
+}
+
+// filterExpressions creates the cost explorer expressions of the filters. Organization filters are converted to
+// linked account filters, returning [errNoMatchingAccounts] if any of them don't match any account, or
+// [errOrganizationNotLoaded] if their organization data could not be loaded.
+func (c *Cloud) filterExpressions(filters []cloudcostexplorer.QueryFilter) ([]types.Expression, error) {
+	var ret []types.Expression
+	for _, filter := range filters {
+		if !isOrganizationParameter(filter.ID) {
+			ret = append(ret, filterExpression(filter))
+			continue
+		}
+		accounts, loaded := c.organizationAccounts(filter)
+		if !loaded {
+			return nil, fmt.Errorf("%w, the %s filter can't be applied", errOrganizationNotLoaded, filter.ID)
+		}
+		if len(accounts) == 0 {
+			return nil, fmt.Errorf("%w: %s=%s", errNoMatchingAccounts, filter.ID, filter.Value)
+		}
+		ret = append(ret, types.Expression{
+			Dimensions: &types.DimensionValues{
+				Key:    types.DimensionLinkedAccount,
+				Values: accounts,
+			},
+		})
+	}
+	return ret, nil
+}
+
+// expressionAccounts returns the accounts matching all the linked account expressions, or nil if there are none.
+// Returns false if no account matches all of them.
+func expressionAccounts(expressions []types.Expression) ([]string, bool) {
+	var ret []string
+	isFiltered := false
+	for _, expression := range expressions {
+		if expression.Dimensions == nil || expression.Dimensions.Key != types.DimensionLinkedAccount {
+			continue
+		}
+		if !isFiltered {
+			ret = slices.Clone(expression.Dimensions.Values)
+			isFiltered = true
+			continue
+		}
+		ret = slices.DeleteFunc(ret, func(account string) bool {
+			return !slices.Contains(expression.Dimensions.Values, account)
+		})
+	}
+	return ret, !isFiltered || len(ret) > 0
+}
+
+// organizationAccounts returns the sorted IDs of the accounts matching an organization filter. OU filters match the
+// OU at any level, and account tag filters have the key and value separated by [cloudcostexplorer.DataSeparator].
+// Returns false if the organization data of the filter was not loaded.
+func (c *Cloud) organizationAccounts(filter cloudcostexplorer.QueryFilter) ([]string, bool) {
+	c.orgMutex.RLock()
+	defer c.orgMutex.RUnlock()
+
+	if !c.org.isLoaded(filter.ID) {
+		return nil, false
+	}
+
+	tagKey, tagValue, _ := strings.Cut(filter.Value, cloudcostexplorer.DataSeparator)
+
+	var ret []string
+	for accountID, account := range c.org.accounts {
+		switch filter.ID {
+		case "OU":
+			if !slices.Contains(account.Path, filter.Value) {
+				continue
+			}
+		case "ACCOUNT_TAG":
+			if value, ok := account.Tags[tagKey]; !ok || value != tagValue {
+				continue
+			}
+		default:
+			continue
+		}
+		ret = append(ret, accountID)
+	}
+	slices.Sort(ret)
+	return ret, true
+}
+
+// loadOrganization loads the organization data. If the accounts can't be listed, the previous data is kept. If only
+// the OUs or the tags fail, the previous OUs or tags are kept for the known accounts.
+func (c *Cloud) loadOrganization(ctx context.Context) {
+	startTime := time.Now()
+	org, err := c.fetchOrganization(ctx)
+	if err != nil {
+		c.logger.ErrorContext(ctx, "error loading organization data", "error", err)
+		if org == nil {
+			return
+		}
+	}
+
+	c.orgMutex.Lock()
+	defer c.orgMutex.Unlock()
+
+	previous := c.org
+	if !org.unitsLoaded {
+		org.units = previous.units
+		org.unitsLoaded = previous.unitsLoaded
+		for accountID, account := range org.accounts {
+			account.Path = nil
+			if previousAccount, ok := previous.accounts[accountID]; ok {
+				account.Path = previousAccount.Path
+			}
+		}
+	}
+	if !org.tagsLoaded {
+		org.tagsLoaded = previous.tagsLoaded
+		for accountID, account := range org.accounts {
+			account.Tags = map[string]string{}
+			if previousAccount, ok := previous.accounts[accountID]; ok {
+				account.Tags = previousAccount.Tags
+			}
+		}
+	}
+	c.org = org
+
+	c.logger.InfoContext(ctx, "organization data loaded",
+		"accounts", len(org.accounts), "units", len(org.units), "duration", time.Since(startTime))
+}
+
+// refreshOrganization reloads the organization data on each interval until the context is done.
+func (c *Cloud) refreshOrganization(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.loadOrganization(ctx)
+		}
+	}
+}
+
+// fetchOrganization fetches the accounts with their tags, and the OU hierarchy. If the account list could be
+// fetched, it is returned even if the OUs or tags fail, as they are not available to all callers, with the failed
+// part marked as not loaded.
+func (c *Cloud) fetchOrganization(ctx context.Context) (*organization, error) {
+	ret := &organization{
+		accounts: map[string]*organizationAccount{},
+		units:    map[string]string{},
+	}
+
+	for data, err := range awsAPIIteratorInput(ctx, &organizations.ListAccountsInput{}, func(ctx context.Context, input *organizations.ListAccountsInput) (*organizations.ListAccountsOutput, error) {
+		return c.orgsClient.ListAccounts(ctx, input)
+	}) {
+		if err != nil {
+			return nil, fmt.Errorf("error listing accounts: %w", err)
+		}
+
+		for _, account := range data.Accounts {
+			ret.accounts[aws.ToString(account.Id)] = &organizationAccount{
+				Name: aws.ToString(account.Name),
+				Tags: map[string]string{},
+			}
+		}
+	}
+
+	var errs []error
+	if err := c.fetchOrganizationUnits(ctx, ret); err != nil {
+		errs = append(errs, err)
+	} else {
+		ret.unitsLoaded = true
+	}
+	if err := c.fetchOrganizationTags(ctx, ret); err != nil {
+		errs = append(errs, err)
+	} else {
+		ret.tagsLoaded = true
+	}
+	return ret, errors.Join(errs...)
+}
+
+// fetchOrganizationUnits fetches the roots and OUs names, and the path of each account.
+func (c *Cloud) fetchOrganizationUnits(ctx context.Context, org *organization) error {
+	parents := map[string]string{} // parent of each OU.
+
+	var listUnits func(parentID string) error
+	listUnits = func(parentID string) error {
+		for data, err := range awsAPIIteratorInput(ctx, &organizations.ListOrganizationalUnitsForParentInput{
+			ParentId: aws.String(parentID),
+		}, func(ctx context.Context, input *organizations.ListOrganizationalUnitsForParentInput) (*organizations.ListOrganizationalUnitsForParentOutput, error) {
+			return c.orgsClient.ListOrganizationalUnitsForParent(ctx, input)
+		}) {
+			if err != nil {
+				return fmt.Errorf("error listing organizational units: %w", err)
+			}
+
+			for _, unit := range data.OrganizationalUnits {
+				unitID := aws.ToString(unit.Id)
+				org.units[unitID] = aws.ToString(unit.Name)
+				parents[unitID] = parentID
+				if err := listUnits(unitID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for data, err := range awsAPIIteratorInput(ctx, &organizations.ListRootsInput{}, func(ctx context.Context, input *organizations.ListRootsInput) (*organizations.ListRootsOutput, error) {
+		return c.orgsClient.ListRoots(ctx, input)
+	}) {
+		if err != nil {
+			return fmt.Errorf("error listing organization roots: %w", err)
+		}
+
+		for _, root := range data.Roots {
+			rootID := aws.ToString(root.Id)
+			org.units[rootID] = aws.ToString(root.Name)
+			if err := listUnits(rootID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for accountID, account := range org.accounts {
+		for data, err := range awsAPIIteratorInput(ctx, &organizations.ListParentsInput{
+			ChildId: aws.String(accountID),
+		}, func(ctx context.Context, input *organizations.ListParentsInput) (*organizations.ListParentsOutput, error) {
+			return c.orgsClient.ListParents(ctx, input)
+		}) {
+			if err != nil {
+				return fmt.Errorf("error listing parents of account '%s': %w", accountID, err)
+			}
+
+			for _, parent := range data.Parents {
+				if parent.Type != orgtypes.ParentTypeOrganizationalUnit && parent.Type != orgtypes.ParentTypeRoot {
+					continue
+				}
+				var path []string
+				for unitID := aws.ToString(parent.Id); unitID != ""; unitID = parents[unitID] {
+					path = append(path, unitID)
+				}
+				slices.Reverse(path)
+				account.Path = path
+			}
+		}
+	}
+
+	return nil
+}
+
+// fetchOrganizationTags fetches the tags of each account.
+func (c *Cloud) fetchOrganizationTags(ctx context.Context, org *organization) error {
+	for accountID, account := range org.accounts {
+		for data, err := range awsAPIIteratorInput(ctx, &organizations.ListTagsForResourceInput{
+			ResourceId: aws.String(accountID),
+		}, func(ctx context.Context, input *organizations.ListTagsForResourceInput) (*organizations.ListTagsForResourceOutput, error) {
+			return c.orgsClient.ListTagsForResource(ctx, input)
+		}) {
+			if err != nil {
+				return fmt.Errorf("error listing tags of account '%s': %w", accountID, err)
+			}
+
+			for _, tag := range data.Tags {
+				account.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+		}
+	}
+	return nil
+}
@@ -37,31 +37,29 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 		// end time is exclusive in cost explorer, must use next day
 		end := optns.End.AddDays(1).String()
 
-		var filters []types.Expression
 		var groups []types.GroupDefinition
 
 		// FILTERS
 
+		var isEC2 bool
+
 		for _, filter := range optns.Filters {
-			if filter.ID == "TAG" {
-				lkey, lval, _ := strings.Cut(filter.Value, cloudcostexplorer.DataSeparator)
-				filters = append(filters, types.Expression{
-					Tags: &types.TagValues{
-						Key:    cloudcostexplorer.Ptr(lkey),
-						Values: []string{lval},
-					},
-				})
-			} else {
-				if filter.ID != "LINKED_ACCOUNT" {
-					isFilter = true
-				}
-				filters = append(filters, types.Expression{
-					Dimensions: &types.DimensionValues{
-						Key:    types.Dimension(strings.ToUpper(filter.ID)),
-						Values: []string{filter.Value},
-					},
-				})
+			if filter.ID != "TAG" && filter.ID != "COST_CATEGORY" && filter.ID != "LINKED_ACCOUNT" &&
+				!isOrganizationParameter(filter.ID) {
+				isFilter = true
 			}
+			if filter.ID == "SERVICE" {
+				isEC2 = filter.Value == ServiceEC2
+			}
+		}
+
+		filters, err := c.filterExpressions(optns.Filters)
+		if errors.Is(err, errNoMatchingAccounts) {
+			// no account matches the organization filters, so there are no costs.
+			return
+		} else if err != nil {
+			yield(cloudcostexplorer.CloudQueryItem{}, err)
+			return
 		}
 
 		extraDataCtx, extraDataCancel := context.WithCancel(ctx)
@@ -69,18 +67,32 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 
 		var usageTypeGroupsFuture chan usageTagGroupsItem
 		var tagsFuture chan tagValueItem
+		var costCategoriesFuture chan tagValueItem
+		var rightsizingFuture chan rightsizingItem
 
-		isExtraData := isFilter && optns.ExtraDataCallback != nil
+		// the extra data makes many API calls, like one for each tag key, skip it if the call budget was exhausted.
+		isExtraData := isFilter && optns.ExtraDataCallback != nil && !c.callBudget.Stats().Exhausted()
 
 		if isExtraData {
 			usageTypeGroupsFuture = usageTagGroupsFuture(extraDataCtx, c.costExplorerClient, start, end, filters)
 			tagsFuture = tagsWithValuesFuture(extraDataCtx, c.costExplorerClient, start, end,
 				buildCostExplorerFilter(filters))
+			costCategoriesFuture = costCategoriesWithValuesFuture(extraDataCtx, c.costExplorerClient, start, end,
+				buildCostExplorerFilter(filters))
+			// the linked account filters include the ones converted from the organization filters.
+			if accounts, ok := expressionAccounts(filters); isEC2 && ok {
+				rightsizingFuture = rightsizingRecommendationsFuture(extraDataCtx, c.costExplorerClient, accounts)
+			}
 		}
 
 		// GROUPS
 
-		for _, group := range optns.Groups {
+		// index of the cost explorer group of each group. Organization groups are computed from the linked account,
+		// so they share the same cost explorer group.
+		groupKeyIdx := make([]int, len(optns.Groups))
+		linkedAccountIdx := -1
+
+		for gidx, group := range optns.Groups {
 			kgroup, kok := c.parameters.FindById(group.ID)
 			if !kok || !kgroup.IsGroup {
 				yield(cloudcostexplorer.CloudQueryItem{}, fmt.Errorf("invalid group '%s'", group.ID))
@@ -91,11 +103,34 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 				isResource = true
 			}
 
+			if group.ID == "LINKED_ACCOUNT" || isOrganizationParameter(group.ID) {
+				if err := validateOrganizationGroup(group); err != nil {
+					yield(cloudcostexplorer.CloudQueryItem{}, err)
+					return
+				}
+				if linkedAccountIdx >= 0 {
+					groupKeyIdx[gidx] = linkedAccountIdx
+					continue
+				}
+				linkedAccountIdx = len(groups)
+			}
+			groupKeyIdx[gidx] = len(groups)
+
 			if group.ID == "TAG" {
 				groups = append(groups, types.GroupDefinition{
 					Key:  aws.String(group.Data),
 					Type: types.GroupDefinitionTypeTag,
 				})
+			} else if group.ID == "COST_CATEGORY" {
+				groups = append(groups, types.GroupDefinition{
+					Key:  aws.String(group.Data),
+					Type: types.GroupDefinitionTypeCostCategory,
+				})
+			} else if isOrganizationParameter(group.ID) {
+				groups = append(groups, types.GroupDefinition{
+					Key:  aws.String("LINKED_ACCOUNT"),
+					Type: types.GroupDefinitionTypeDimension,
+				})
 			} else {
 				groups = append(groups, types.GroupDefinition{
 					Key:  aws.String(group.ID),
@@ -104,6 +139,18 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 			}
 		}
 
+		granularity := types.GranularityDaily
+		if optns.InvoiceMonth {
+			if isResource {
+				yield(cloudcostexplorer.CloudQueryItem{}, errors.New("resource grouping is not supported by invoice month queries"))
+				return
+			}
+			// the cost explorer has no invoice dimension, so the invoice month is approximated by the usage month.
+			granularity = types.GranularityMonthly
+			start = cloudcostexplorer.StartOfMonth(optns.Start).String()
+			end = cloudcostexplorer.StartOfMonth(optns.End).Add(0, 1, 0).String()
+		}
+
 		if isResource {
 			// resource grouping have a max of 14 days
 			minStart := timex.Today(time.UTC).AddDays(-13)
@@ -113,20 +160,29 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 			}
 		}
 
+		c.logger.DebugContext(ctx, "cost explorer query",
+			"start", start, "end", end, "granularity", granularity, "groups", optns.Groups, "filters", optns.Filters,
+			"resource", isResource, "extra_data", isExtraData)
+		queryStart := time.Now()
+		var queryItems int
+
 		var costIter costAndUsageIter
 		if isResource {
-			costIter = costAndUsageWithResources(ctx, c.costExplorerClient, costmetric, start, end,
+			costIter = costAndUsageWithResources(ctx, c.logger, optns.QueryInfoCallback, c.costExplorerClient, costmetric, start, end, granularity,
 				buildCostExplorerFilter(filters), groups)
 		} else {
-			costIter = costAndUsage(ctx, c.costExplorerClient, costmetric, start, end,
+			costIter = costAndUsage(ctx, c.logger, optns.QueryInfoCallback, c.costExplorerClient, costmetric, start, end, granularity,
 				buildCostExplorerFilter(filters), groups)
 		}
 
 		for groupValue, err := range costIter {
 			if err != nil {
+				c.logger.ErrorContext(ctx, "cost explorer query failed",
+					"error", err, "duration", time.Since(queryStart))
 				yield(cloudcostexplorer.CloudQueryItem{}, err)
 				return
 			}
+			queryItems++
 
 			cost, err := strconv.ParseFloat(*groupValue.group.Metrics[costmetric].Amount, 64)
 			if err != nil {
@@ -143,7 +199,7 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 
 			var itemKeys []cloudcostexplorer.ItemKey
 			for groupIdx, group := range optns.Groups {
-				groupName := groupValue.group.Keys[groupIdx]
+				groupName := groupValue.group.Keys[groupKeyIdx[groupIdx]]
 
 				key := cloudcostexplorer.ItemKey{
 					ID:    groupName,
@@ -152,10 +208,12 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 
 				switch group.ID {
 				case "LINKED_ACCOUNT":
-					if la, ok := c.linkedAccounts[groupName]; ok {
+					if la, ok := c.accountName(groupName); ok {
 						key.Value = la
 					}
-				case "TAG":
+				case "OU", "ACCOUNT_TAG":
+					key = c.organizationKey(group, groupName)
+				case "TAG", "COST_CATEGORY":
 					if tn, tv, ok := strings.Cut(groupName, "$"); ok {
 						key.ID = fmt.Sprintf("%s%s%s", tn, cloudcostexplorer.DataSeparator, tv)
 						key.Value = tv
@@ -174,6 +232,9 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 			}
 		}
 
+		c.logger.InfoContext(ctx, "cost explorer query finished",
+			"start", start, "end", end, "items", queryItems, "duration", time.Since(queryStart))
+
 		if isExtraData {
 			if usageTypeGroupsFuture != nil {
 				ed := &extraDataUsageTypeGroups{}
@@ -196,21 +257,15 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 			}
 
 			if tagsFuture != nil {
-				ed := &extraDataTags{
-					data: map[string]*extraDataTag{},
-				}
-				for tt := range tagsFuture {
-					if tt.err != nil {
-						ed.err = fmt.Errorf("error getting tags: %w", tt.err)
-						break
-					}
-					tag := tt.value
-					ed.data[tag.Name] = &extraDataTag{
-						Name:   tag.Name,
-						Values: tag.Values,
-					}
-				}
-				optns.ExtraDataCallback(ed)
+				optns.ExtraDataCallback(newExtraDataTags("TAG", "tags", tagsFuture))
+			}
+
+			if costCategoriesFuture != nil {
+				optns.ExtraDataCallback(newExtraDataTags("COST_CATEGORY", "cost categories", costCategoriesFuture))
+			}
+
+			if rightsizingFuture != nil {
+				optns.ExtraDataCallback(newExtraDataRightsizing(rightsizingFuture))
 			}
 		}
 	}
@@ -0,0 +1,214 @@
+package aws
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+)
+
+// ServiceEC2 is the cost explorer SERVICE dimension value of EC2 instances.
+const ServiceEC2 = "Amazon Elastic Compute Cloud - Compute"
+
+// ServiceRDS is the cost explorer SERVICE dimension value of RDS instances.
+const ServiceRDS = "Amazon Relational Database Service"
+
+// ReservationServices are the services supported by the reservation purchase recommendations, using the cost
+// explorer SERVICE dimension values.
+var ReservationServices = []string{
+	ServiceEC2,
+	ServiceRDS,
+	"Amazon ElastiCache",
+	"Amazon Redshift",
+	"Amazon OpenSearch Service",
+	"Amazon MemoryDB",
+}
+
+// PurchaseRecommendationQuery is the query for the Savings Plans and reservation purchase recommendations.
+type PurchaseRecommendationQuery struct {
+	SavingsPlansType   types.SupportedSavingsPlansType
+	ReservationService string // one of ReservationServices.
+	Term               types.TermInYears
+	PaymentOption      types.PaymentOption
+	LookbackPeriod     types.LookbackPeriodInDays
+}
+
+// PurchaseRecommendation is a single Savings Plans or reservation purchase recommendation.
+type PurchaseRecommendation struct {
+	AccountID      string
+	Service        string // cost explorer SERVICE dimension value, blank if it applies to multiple services.
+	InstanceFamily string
+	InstanceType   string
+	Region         string
+
+	HourlyCommitment           float64 // Savings Plans only, the hourly commitment to purchase.
+	Quantity                   float64 // reservations only, the number of instances to purchase.
+	UpfrontCost                float64
+	EstimatedMonthlySavings    float64
+	EstimatedSavingsPercentage float64
+	BreakEvenMonths            float64 // number of months until the savings pay for the upfront cost.
+}
+
+// PurchaseRecommendations are the Savings Plans and reservation purchase recommendations. Errors of each API are
+// returned separately.
+type PurchaseRecommendations struct {
+	SavingsPlansErr  error
+	SavingsPlans     []*PurchaseRecommendation
+	ReservationsErr  error
+	Reservations     []*PurchaseRecommendation
+	LookbackDays     int
+	SavingsPlansType types.SupportedSavingsPlansType
+}
+
+// LookbackDays returns the number of days of a lookback period.
+func LookbackDays(period types.LookbackPeriodInDays) int {
+	switch period {
+	case types.LookbackPeriodInDaysSevenDays:
+		return 7
+	case types.LookbackPeriodInDaysSixtyDays:
+		return 60
+	default:
+		return 30
+	}
+}
+
+// PurchaseRecommendations returns the Savings Plans and reservation purchase recommendations, sorted by the
+// estimated monthly savings.
+func (c *Cloud) PurchaseRecommendations(ctx context.Context, query PurchaseRecommendationQuery) *PurchaseRecommendations {
+	ret := &PurchaseRecommendations{
+		LookbackDays:     LookbackDays(query.LookbackPeriod),
+		SavingsPlansType: query.SavingsPlansType,
+	}
+	ret.SavingsPlans, ret.SavingsPlansErr = c.savingsPlansPurchaseRecommendations(ctx, query)
+	ret.Reservations, ret.ReservationsErr = c.reservationPurchaseRecommendations(ctx, query)
+	return ret
+}
+
+func (c *Cloud) savingsPlansPurchaseRecommendations(ctx context.Context, query PurchaseRecommendationQuery) ([]*PurchaseRecommendation, error) {
+	var ret []*PurchaseRecommendation
+
+	for data, err := range awsAPIIteratorInput(ctx, &costexplorer.GetSavingsPlansPurchaseRecommendationInput{
+		SavingsPlansType:     query.SavingsPlansType,
+		TermInYears:          query.Term,
+		PaymentOption:        query.PaymentOption,
+		LookbackPeriodInDays: query.LookbackPeriod,
+	}, func(ctx context.Context, input *costexplorer.GetSavingsPlansPurchaseRecommendationInput) (*costexplorer.GetSavingsPlansPurchaseRecommendationOutput, error) {
+		return c.costExplorerClient.GetSavingsPlansPurchaseRecommendation(ctx, input)
+	}) {
+		if err != nil {
+			return nil, fmt.Errorf("error getting savings plans purchase recommendations: %w", err)
+		}
+		if data.SavingsPlansPurchaseRecommendation == nil {
+			continue
+		}
+
+		for _, detail := range data.SavingsPlansPurchaseRecommendation.SavingsPlansPurchaseRecommendationDetails {
+			item := &PurchaseRecommendation{
+				AccountID: aws.ToString(detail.AccountId),
+			}
+			if detail.SavingsPlansDetails != nil {
+				item.InstanceFamily = aws.ToString(detail.SavingsPlansDetails.InstanceFamily)
+				item.Region = aws.ToString(detail.SavingsPlansDetails.Region)
+			}
+			if query.SavingsPlansType == types.SupportedSavingsPlansTypeEc2InstanceSp {
+				item.Service = ServiceEC2
+			}
+			err := parseAmounts(map[*float64]*string{
+				&item.HourlyCommitment:           detail.HourlyCommitmentToPurchase,
+				&item.UpfrontCost:                detail.UpfrontCost,
+				&item.EstimatedMonthlySavings:    detail.EstimatedMonthlySavingsAmount,
+				&item.EstimatedSavingsPercentage: detail.EstimatedSavingsPercentage,
+			})
+			if err != nil {
+				return nil, err
+			}
+			// the API doesn't return the break-even point for savings plans.
+			if item.UpfrontCost > 0 && item.EstimatedMonthlySavings > 0 {
+				item.BreakEvenMonths = item.UpfrontCost / item.EstimatedMonthlySavings
+			}
+			ret = append(ret, item)
+		}
+	}
+
+	sortPurchaseRecommendations(ret)
+	return ret, nil
+}
+
+func (c *Cloud) reservationPurchaseRecommendations(ctx context.Context, query PurchaseRecommendationQuery) ([]*PurchaseRecommendation, error) {
+	var ret []*PurchaseRecommendation
+
+	for data, err := range awsAPIIteratorInput(ctx, &costexplorer.GetReservationPurchaseRecommendationInput{
+		Service:              aws.String(query.ReservationService),
+		TermInYears:          query.Term,
+		PaymentOption:        query.PaymentOption,
+		LookbackPeriodInDays: query.LookbackPeriod,
+	}, func(ctx context.Context, input *costexplorer.GetReservationPurchaseRecommendationInput) (*costexplorer.GetReservationPurchaseRecommendationOutput, error) {
+		return c.costExplorerClient.GetReservationPurchaseRecommendation(ctx, input)
+	}) {
+		if err != nil {
+			return nil, fmt.Errorf("error getting reservation purchase recommendations: %w", err)
+		}
+
+		for _, recommendation := range data.Recommendations {
+			for _, detail := range recommendation.RecommendationDetails {
+				item := &PurchaseRecommendation{
+					AccountID: aws.ToString(detail.AccountId),
+					Service:   query.ReservationService,
+				}
+				item.InstanceFamily, item.InstanceType, item.Region = reservationInstanceDetails(detail.InstanceDetails)
+				err := parseAmounts(map[*float64]*string{
+					&item.Quantity:                   detail.RecommendedNumberOfInstancesToPurchase,
+					&item.UpfrontCost:                detail.UpfrontCost,
+					&item.EstimatedMonthlySavings:    detail.EstimatedMonthlySavingsAmount,
+					&item.EstimatedSavingsPercentage: detail.EstimatedMonthlySavingsPercentage,
+					&item.BreakEvenMonths:            detail.EstimatedBreakEvenInMonths,
+				})
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, item)
+			}
+		}
+	}
+
+	sortPurchaseRecommendations(ret)
+	return ret, nil
+}
+
+// reservationInstanceDetails returns the instance family, type and region of the reserved instance.
+func reservationInstanceDetails(details *types.InstanceDetails) (family, instanceType, region string) {
+	switch {
+	case details == nil:
+		return "", "", ""
+	case details.EC2InstanceDetails != nil:
+		d := details.EC2InstanceDetails
+		return aws.ToString(d.Family), aws.ToString(d.InstanceType), aws.ToString(d.Region)
+	case details.RDSInstanceDetails != nil:
+		d := details.RDSInstanceDetails
+		return aws.ToString(d.Family), aws.ToString(d.InstanceType), aws.ToString(d.Region)
+	case details.ElastiCacheInstanceDetails != nil:
+		d := details.ElastiCacheInstanceDetails
+		return aws.ToString(d.Family), aws.ToString(d.NodeType), aws.ToString(d.Region)
+	case details.RedshiftInstanceDetails != nil:
+		d := details.RedshiftInstanceDetails
+		return aws.ToString(d.Family), aws.ToString(d.NodeType), aws.ToString(d.Region)
+	case details.ESInstanceDetails != nil:
+		d := details.ESInstanceDetails
+		return aws.ToString(d.InstanceClass), aws.ToString(d.InstanceSize), aws.ToString(d.Region)
+	case details.MemoryDBInstanceDetails != nil:
+		d := details.MemoryDBInstanceDetails
+		return aws.ToString(d.Family), aws.ToString(d.NodeType), aws.ToString(d.Region)
+	default:
+		return "", "", ""
+	}
+}
+
+func sortPurchaseRecommendations(items []*PurchaseRecommendation) {
+	slices.SortStableFunc(items, func(a, b *PurchaseRecommendation) int {
+		return cmp.Compare(b.EstimatedMonthlySavings, a.EstimatedMonthlySavings)
+	})
+}
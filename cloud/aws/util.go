@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"iter"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/rrgmc/cloudcostexplorer"
 )
 
 // buildCostExplorerFilter creates a cost explorer [types.Expression] from a list of [types.Expression].
@@ -23,6 +26,36 @@ func buildCostExplorerFilter(filter []types.Expression) *types.Expression {
 	}
 }
 
+// filterExpression creates a cost explorer [types.Expression] from a filter. Tag and cost category filters have the
+// key and value separated by [cloudcostexplorer.DataSeparator].
+func filterExpression(filter cloudcostexplorer.QueryFilter) types.Expression {
+	switch filter.ID {
+	case "TAG":
+		lkey, lval, _ := strings.Cut(filter.Value, cloudcostexplorer.DataSeparator)
+		return types.Expression{
+			Tags: &types.TagValues{
+				Key:    cloudcostexplorer.Ptr(lkey),
+				Values: []string{lval},
+			},
+		}
+	case "COST_CATEGORY":
+		lkey, lval, _ := strings.Cut(filter.Value, cloudcostexplorer.DataSeparator)
+		return types.Expression{
+			CostCategories: &types.CostCategoryValues{
+				Key:    cloudcostexplorer.Ptr(lkey),
+				Values: []string{lval},
+			},
+		}
+	default:
+		return types.Expression{
+			Dimensions: &types.DimensionValues{
+				Key:    types.Dimension(strings.ToUpper(filter.ID)),
+				Values: []string{filter.Value},
+			},
+		}
+	}
+}
+
 // awsAPIIteratorInput iterates on AWS APIs which takes a single input struct and returns a single output struct.
 func awsAPIIteratorInput[I, O any](ctx context.Context, input *I, nextPage func(ctx context.Context,
 	input *I) (*O, error)) iter.Seq2[*O, error] {
@@ -111,3 +144,19 @@ func awsAPISetNextPageToken(in any, token *string) error {
 	npt.Set(reflect.ValueOf(token))
 	return nil
 }
+
+// parseAmounts parses AWS string amounts into the destination values. Nil amounts are set to 0.
+func parseAmounts(amounts map[*float64]*string) error {
+	for dest, amount := range amounts {
+		if amount == nil || *amount == "" {
+			*dest = 0
+			continue
+		}
+		value, err := strconv.ParseFloat(*amount, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing amount '%s': %w", *amount, err)
+		}
+		*dest = value
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/dustin/go-humanize"
+	"github.com/rrgmc/cloudcostexplorer"
+	"google.golang.org/api/iterator"
+)
+
+// labelParameters are the parameters which are label fields, in the order they are shown.
+var labelParameters = []string{"LABEL", "SYSLABEL", "TAGS", "PROJECT_LABEL"}
+
+// maxLabelValues is the maximum number of values returned for each label key.
+const maxLabelValues = 100
+
+type labelKey struct {
+	ParamID string
+	Key     string
+	Values  []string
+}
+
+// labelKeys returns the label, system label, tag and project label keys with their values, using the passed joins and where clause
+// to filter the billing data.
+func (c *Cloud) labelKeys(ctx context.Context, joinAdd, where string,
+	queryParameters []bigquery.QueryParameter, confirmed bool) iter.Seq2[labelKey, error] {
+	return func(yield func(labelKey, error) bool) {
+		var queries []string
+		for _, paramID := range labelParameters {
+			queries = append(queries, fmt.Sprintf(`SELECT
+    '%s' AS param, extra_labels.key AS label_key,
+    ARRAY_AGG(DISTINCT IFNULL(extra_labels.value, '') ORDER BY IFNULL(extra_labels.value, '') LIMIT %d) AS label_values
+FROM
+	%s
+	%s
+	JOIN UNNEST(%s) AS extra_labels
+WHERE
+    %s
+GROUP BY extra_labels.key`, paramID, maxLabelValues, c.resourceTableName, joinAdd, labelField(paramID), where))
+		}
+
+		queryStart := time.Now()
+		defer func() {
+			c.logger.DebugContext(ctx, "BigQuery label keys query finished", "duration", time.Since(queryStart))
+		}()
+
+		it, err := c.runQuery(ctx, strings.Join(queries, "\nUNION ALL\n")+"\nORDER BY param, label_key",
+			queryParameters, confirmed, nil)
+		if err != nil {
+			c.logger.ErrorContext(ctx, "BigQuery label keys query failed", "error", err)
+			yield(labelKey{}, fmt.Errorf("error querying BigQuery: %w", err))
+			return
+		}
+
+		var row map[string]bigquery.Value
+		for {
+			err := it.Next(&row)
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				yield(labelKey{}, fmt.Errorf("error iterating BigQuery: %w", err))
+				return
+			}
+
+			item := labelKey{
+				ParamID: bigQueryStringValue(row, "param"),
+				Key:     bigQueryStringValue(row, "label_key"),
+			}
+			if values, ok := row["label_values"].([]bigquery.Value); ok {
+				for _, value := range values {
+					item.Values = append(item.Values, fmt.Sprint(value))
+				}
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+type labelKeyItem struct {
+	value labelKey
+	err   error
+}
+
+// labelKeysFuture returns labelKeys as a channel.
+func (c *Cloud) labelKeysFuture(ctx context.Context, joinAdd, where string,
+	queryParameters []bigquery.QueryParameter, confirmed bool) chan labelKeyItem {
+	ch := make(chan labelKeyItem, 100)
+	go func() {
+		defer close(ch)
+		for lk, err := range c.labelKeys(ctx, joinAdd, where, queryParameters, confirmed) {
+			if err != nil {
+				select {
+				case ch <- labelKeyItem{err: fmt.Errorf("couldn't fetch label data: %w", err)}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			select {
+			case ch <- labelKeyItem{value: lk}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// newQuery creates a BigQuery query with the configured job settings.
+func (c *Cloud) newQuery(sql string, parameters []bigquery.QueryParameter) *bigquery.Query {
+	query := c.bigQueryClient.Query(sql)
+	query.Parameters = parameters
+	query.Location = c.jobLocation
+	query.Priority = c.jobPriority
+	query.Labels = c.jobLabels
+	query.MaxBytesBilled = c.maxBytesBilled
+	return query
+}
+
+// runQuery runs a BigQuery query and waits for it to finish, sending its SQL, parameters and job statistics to the
+// callback if set, even if the query fails.
+// If a maximum of bytes scanned is configured, the query is dry-run first and returns a
+// [cloudcostexplorer.ConfirmationRequiredError] if the estimate is above it, unless it was confirmed.
+func (c *Cloud) runQuery(ctx context.Context, sql string, parameters []bigquery.QueryParameter, confirmed bool,
+	queryInfoCallback func(cloudcostexplorer.QueryInfo)) (*bigquery.RowIterator, error) {
+	startTime := time.Now()
+	var estimatedBytes int64
+	var job *bigquery.Job
+	var status *bigquery.JobStatus
+	if queryInfoCallback != nil {
+		defer func() {
+			info := bigQueryQueryInfo(sql, parameters, job, status, time.Since(startTime))
+			if estimatedBytes > 0 {
+				info.Stats = append([]cloudcostexplorer.QueryInfoValue{{
+					Name:  "Estimated bytes (dry run)",
+					Value: humanize.IBytes(uint64(estimatedBytes)),
+				}}, info.Stats...)
+			}
+			queryInfoCallback(info)
+		}()
+	}
+
+	if c.maxBytesScanned > 0 && !confirmed {
+		var err error
+		estimatedBytes, err = c.dryRunQuery(ctx, sql, parameters)
+		if err != nil {
+			return nil, err
+		}
+		if estimatedBytes > c.maxBytesScanned {
+			c.logger.WarnContext(ctx, "BigQuery query requires confirmation",
+				"estimated_bytes", estimatedBytes, "max_bytes_scanned", c.maxBytesScanned)
+			return nil, &cloudcostexplorer.ConfirmationRequiredError{
+				Message: fmt.Sprintf("the query will scan an estimated %s, above the maximum of %s",
+					humanize.IBytes(uint64(estimatedBytes)), humanize.IBytes(uint64(c.maxBytesScanned))),
+			}
+		}
+	}
+
+	job, err := c.newQuery(sql, parameters).Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err = job.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+	return job.Read(ctx)
+}
+
+// dryRunQuery returns the estimated number of bytes the query will scan.
+func (c *Cloud) dryRunQuery(ctx context.Context, sql string, parameters []bigquery.QueryParameter) (int64, error) {
+	query := c.newQuery(sql, parameters)
+	query.DryRun = true
+	job, err := query.Run(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error estimating query size: %w", err)
+	}
+	status := job.LastStatus()
+	if status == nil || status.Statistics == nil {
+		return 0, errors.New("error estimating query size: no statistics returned")
+	}
+	return status.Statistics.TotalBytesProcessed, nil
+}
+
+// bigQueryQueryInfo returns the debug information of a BigQuery query. The job and status may be nil if the query
+// failed to start.
+func bigQueryQueryInfo(sql string, parameters []bigquery.QueryParameter, job *bigquery.Job, status *bigquery.JobStatus,
+	latency time.Duration) cloudcostexplorer.QueryInfo {
+	ret := cloudcostexplorer.QueryInfo{
+		Title:     "BigQuery",
+		Query:     sql,
+		IsSQL:     true,
+		CopyQuery: inlineQueryParameters(sql, parameters),
+	}
+	for _, parameter := range parameters {
+		ret.Parameters = append(ret.Parameters, cloudcostexplorer.QueryInfoValue{
+			Name:  "@" + parameter.Name,
+			Value: fmt.Sprint(parameter.Value),
+		})
+	}
+	if job != nil {
+		ret.Stats = append(ret.Stats,
+			cloudcostexplorer.QueryInfoValue{Name: "Job ID", Value: job.ID()},
+			cloudcostexplorer.QueryInfoValue{Name: "Location", Value: job.Location()},
+		)
+	}
+	if status != nil && status.Statistics != nil {
+		ret.Stats = append(ret.Stats, cloudcostexplorer.QueryInfoValue{
+			Name:  "Bytes processed",
+			Value: humanize.IBytes(uint64(status.Statistics.TotalBytesProcessed)),
+		})
+		if details, ok := status.Statistics.Details.(*bigquery.QueryStatistics); ok {
+			ret.Stats = append(ret.Stats,
+				cloudcostexplorer.QueryInfoValue{Name: "Bytes billed", Value: humanize.IBytes(uint64(details.TotalBytesBilled))},
+				cloudcostexplorer.QueryInfoValue{Name: "Slot time", Value: (time.Duration(details.SlotMillis) * time.Millisecond).String()},
+				cloudcostexplorer.QueryInfoValue{Name: "Cache hit", Value: strconv.FormatBool(details.CacheHit)},
+			)
+		}
+	}
+	ret.Stats = append(ret.Stats, cloudcostexplorer.QueryInfoValue{
+		Name:  "Latency",
+		Value: latency.Round(time.Millisecond).String(),
+	})
+	return ret
+}
+
+var (
+	// queryParameterRegexp matches the named parameters of a query.
+	queryParameterRegexp = regexp.MustCompile(`@(\w+)`)
+	// sqlStringEscaper escapes a value to be used in a single-quoted string literal.
+	sqlStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+)
+
+// inlineQueryParameters returns the query with the named parameters replaced by their values as literals, so it can
+// be run in the BigQuery console. String literals are coerced to the compared type, like TIMESTAMP.
+func inlineQueryParameters(sql string, parameters []bigquery.QueryParameter) string {
+	values := map[string]any{}
+	for _, parameter := range parameters {
+		values[parameter.Name] = parameter.Value
+	}
+	return queryParameterRegexp.ReplaceAllStringFunc(sql, func(match string) string {
+		value, ok := values[match[1:]]
+		if !ok {
+			return match
+		}
+		switch v := value.(type) {
+		case string:
+			return "'" + sqlStringEscaper.Replace(v) + "'"
+		default:
+			return fmt.Sprint(v)
+		}
+	})
+}
@@ -3,6 +3,8 @@ package gcp
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/google/uuid"
@@ -15,6 +17,14 @@ type Cloud struct {
 	defaultTableName  string
 	resourceTableName string
 	blankKeyValue     string
+	location          *time.Location
+	logger            *slog.Logger
+
+	maxBytesScanned int64
+	maxBytesBilled  int64
+	jobLocation     string
+	jobPriority     bigquery.QueryPriority
+	jobLabels       map[string]string
 
 	parameters      cloudcostexplorer.Parameters
 	parameterValues map[string]parameterValues
@@ -28,6 +38,8 @@ func New(ctx context.Context, options ...CloudOption) (*Cloud, error) {
 		resourceTableName: "billing_export.gcp_billing_export_resource_v1",
 		parameterValues:   make(map[string]parameterValues),
 		blankKeyValue:     fmt.Sprintf("blank_value_%s", uuid.New().String()),
+		location:          time.UTC,
+		logger:            slog.Default(),
 	}
 	ret.load()
 	for _, opt := range options {
@@ -72,7 +84,10 @@ func (c *Cloud) ParameterTitle(id string, defaultValue string) string {
 	if !ok {
 		return defaultValue
 	}
-	return d.values[defaultValue]
+	if title, ok := d.values[defaultValue]; ok && title != "" {
+		return title
+	}
+	return defaultValue
 }
 
 func (c *Cloud) load() {
@@ -108,6 +123,91 @@ func (c *Cloud) load() {
 			IsGroupFilter:   true,
 			IsFilter:        true,
 		},
+		{
+			ID:            "PROJECT_NUMBER",
+			Name:          "Project number",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "BILLING_ACCOUNT",
+			Name:          "Billing account",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "SERVICE_DESCRIPTION",
+			Name:          "Service description",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "SKU_DESCRIPTION",
+			Name:          "SKU description",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "ZONE",
+			Name:          "Zone",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "COUNTRY",
+			Name:          "Country",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "LOCATION",
+			Name:          "Location",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "PRICING_UNIT",
+			Name:          "Pricing unit",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "ADJUSTMENT_TYPE",
+			Name:          "Adjustment type",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "TRANSACTION_TYPE",
+			Name:          "Transaction type",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "FOLDER",
+			Name:          "Folder",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+			HasData:       true,
+		},
+		{
+			ID:            "ORG",
+			Name:          "Organization",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
 		{
 			ID:            "COSTTYPE",
 			Name:          "Cost type",
@@ -122,6 +222,20 @@ func (c *Cloud) load() {
 			IsGroupFilter: true,
 			IsFilter:      true,
 		},
+		{
+			ID:            "CREDIT_TYPE",
+			Name:          "Credit type",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
+		{
+			ID:            "CREDIT",
+			Name:          "Credit",
+			IsGroup:       true,
+			IsGroupFilter: true,
+			IsFilter:      true,
+		},
 		{
 			ID:            "LABEL",
 			Name:          "Label",
@@ -146,5 +260,13 @@ func (c *Cloud) load() {
 			IsFilter:      true,
 			HasData:       true,
 		},
+		{
+			ID:            "PROJECT_LABEL",
+			Name:          "Project label",
+			IsGroup:       true,
+			IsGroupFilter: false,
+			IsFilter:      true,
+			HasData:       true,
+		},
 	}
 }
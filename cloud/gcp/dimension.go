@@ -0,0 +1,47 @@
+package gcp
+
+// dimension is a parameter which maps directly to billing export fields, used for both grouping and filtering.
+// All fields are available on both the standard and resource tables, unless resourceTable is set.
+type dimension struct {
+	idField          string // field used for grouping and filtering.
+	descriptionField string // field shown when grouping, if different from idField.
+	resourceTable    bool   // the field is only available on the resource table.
+	costLines        bool   // the field is only available after the cost lines join.
+}
+
+// groupFields returns the fields to be used in the GROUP BY clause.
+func (d dimension) groupFields() string {
+	if d.descriptionField == "" {
+		return d.idField
+	}
+	return d.idField + ", " + d.descriptionField
+}
+
+// description returns the field to be used as the group description.
+func (d dimension) description() string {
+	if d.descriptionField == "" {
+		return d.idField
+	}
+	return d.descriptionField
+}
+
+var dimensions = map[string]dimension{
+	"PROJECT":             {idField: "project.id", descriptionField: "project.name"},
+	"PROJECT_NUMBER":      {idField: "project.number", descriptionField: "project.name"},
+	"SERVICE":             {idField: "service.id", descriptionField: "service.description"},
+	"SERVICE_DESCRIPTION": {idField: "service.description"},
+	"SKU":                 {idField: "sku.id", descriptionField: "sku.description"},
+	"SKU_DESCRIPTION":     {idField: "sku.description"},
+	"BILLING_ACCOUNT":     {idField: "billing_account_id"},
+	"REGION":              {idField: "location.region"},
+	"ZONE":                {idField: "location.zone"},
+	"COUNTRY":             {idField: "location.country"},
+	"LOCATION":            {idField: "location.location"},
+	"PRICING_UNIT":        {idField: "usage.pricing_unit"},
+	"COSTTYPE":            {idField: "cost_type"},
+	"ADJUSTMENT_TYPE":     {idField: "adjustment_info.type"},
+	"TRANSACTION_TYPE":    {idField: "transaction_type"},
+	"RESOURCE":            {idField: "resource.global_name", descriptionField: "resource.name", resourceTable: true},
+	"CREDIT_TYPE":         {idField: "cost_lines.type", costLines: true},
+	"CREDIT":              {idField: "cost_lines.name", costLines: true},
+}
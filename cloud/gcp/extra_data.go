@@ -0,0 +1,103 @@
+package gcp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/rrgmc/cloudcostexplorer"
+)
+
+type extraDataLabels struct {
+	err  error
+	data map[string]*extraDataLabel
+}
+
+func (e *extraDataLabels) ExtraDataType() string {
+	return "LABEL"
+}
+
+func (e *extraDataLabels) add(value labelKey) {
+	e.merge(&extraDataLabels{
+		data: map[string]*extraDataLabel{
+			fmt.Sprintf("%s%s%s", value.ParamID, cloudcostexplorer.DataSeparator, value.Key): {
+				ParamID: value.ParamID,
+				Key:     value.Key,
+				Values:  value.Values,
+			},
+		},
+	})
+}
+
+func (e *extraDataLabels) merge(other *extraDataLabels) {
+	if other.err != nil {
+		e.err = errors.Join(e.err, other.err)
+	} else {
+		for ln, lv := range other.data {
+			curv, ok := e.data[ln]
+			if !ok {
+				e.data[ln] = lv
+			} else {
+				for _, v := range lv.Values {
+					if slices.Contains(curv.Values, v) {
+						continue
+					}
+					curv.Values = append(curv.Values, v)
+				}
+			}
+		}
+	}
+}
+
+type extraDataLabel struct {
+	ParamID string
+	Key     string
+	Values  []string
+}
+
+// QueryExtraOutput outputs a list of labels, system labels, tags and project labels available for the current filter.
+func (c *Cloud) QueryExtraOutput(ctx context.Context, extraData []cloudcostexplorer.QueryExtraData) cloudcostexplorer.QueryExtraOutput {
+	out := &extraOutput{}
+
+	edLabels := extraDataLabels{
+		data: make(map[string]*extraDataLabel),
+	}
+
+	for _, data := range extraData {
+		switch dt := data.(type) {
+		case *extraDataLabels:
+			edLabels.merge(dt)
+		}
+	}
+
+	if edLabels.err != nil {
+		out.labels = append(out.labels, &extraOutputLabels{
+			paramID: labelParameters[0],
+			err:     edLabels.err,
+		})
+		return out
+	}
+
+	labels := slices.SortedFunc(maps.Values(edLabels.data), func(a, b *extraDataLabel) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	for _, paramID := range labelParameters {
+		ol := &extraOutputLabels{
+			paramID: paramID,
+		}
+		for _, label := range labels {
+			if label.ParamID == paramID {
+				slices.Sort(label.Values)
+				ol.data = append(ol.data, label)
+			}
+		}
+		if len(ol.data) > 0 {
+			out.labels = append(out.labels, ol)
+		}
+	}
+
+	return out
+}
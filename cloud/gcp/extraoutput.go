@@ -0,0 +1,106 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/rrgmc/cloudcostexplorer"
+)
+
+type extraOutput struct {
+	labels []*extraOutputLabels
+}
+
+func (e extraOutput) Close() {
+}
+
+func (e extraOutput) ExtraOutputs() iter.Seq2[cloudcostexplorer.ValueOutput, error] {
+	return func(yield func(cloudcostexplorer.ValueOutput, error) bool) {
+		for _, labels := range e.labels {
+			if !yield(labels, nil) {
+				return
+			}
+		}
+	}
+}
+
+type extraOutputLabels struct {
+	paramID string
+	err     error
+	data    []*extraDataLabel
+}
+
+func (e extraOutputLabels) title() string {
+	switch e.paramID {
+	case "SYSLABEL":
+		return "System labels"
+	case "TAGS":
+		return "Tags"
+	case "PROJECT_LABEL":
+		return "Project labels"
+	default:
+		return "Labels"
+	}
+}
+
+func (e extraOutputLabels) Output(ctx context.Context, vctx cloudcostexplorer.ValueContext, uq *cloudcostexplorer.URLQuery) (string, error) {
+	var sb strings.Builder
+
+	if e.err != nil {
+		_, _ = sb.WriteString(fmt.Sprintf(`<h3>%s</h3>`, e.title()))
+		_, _ = sb.WriteString(fmt.Sprintf(`<p>error: %s</p>`, e.err.Error()))
+		return sb.String(), nil
+	}
+
+	if len(e.data) == 0 {
+		return "", nil
+	}
+
+	_, _ = sb.WriteString(fmt.Sprintf(`<h3>%s</h3>`, e.title()))
+	vctx.Flush()
+
+	_, _ = sb.WriteString(`<table class="table table-striped table-bordered">`)
+	_, _ = sb.WriteString(`<thead><th>Key</th><th>Values</th></thead><tbody>`)
+
+	for _, label := range e.data {
+		_, _ = sb.WriteString(fmt.Sprintf(`<tr><td><a href="%s">%s</a></td><td>`,
+			uq.Clone().Set("group2", fmt.Sprintf("%s%s%s", e.paramID, cloudcostexplorer.DataSeparator, label.Key)),
+			label.Key))
+
+		isCollapsed := len(label.Values) > 10
+		collapsedID := cloudcostexplorer.RandString(10)
+
+		if isCollapsed {
+			_, _ = sb.WriteString(fmt.Sprintf(`<a class="btn btn-primary" data-bs-toggle="collapse" href="#%s" role="button" aria-expanded="false" aria-controls="collapseExample">
+    View %d</a>`, collapsedID, len(label.Values)))
+			_, _ = sb.WriteString(fmt.Sprintf(`<div class="collapse" id="%s">`, collapsedID))
+		}
+
+		_, _ = sb.WriteString(`<ul class="list-group">`)
+		for _, labelValue := range label.Values {
+			lv := labelValue
+			if lv == "" {
+				lv = "[BLANK]"
+			}
+
+			_, _ = sb.WriteString(fmt.Sprintf(`<li class="list-group-item"><a href="%s">%s</a></li>`+"\n",
+				uq.Clone().Add(vctx.FilterParamName(e.paramID), fmt.Sprintf("%s%s%s", label.Key, cloudcostexplorer.DataSeparator, labelValue)),
+				lv))
+		}
+		_, _ = sb.WriteString(`</ul>`)
+
+		if isCollapsed {
+			_, _ = sb.WriteString(`</div>`)
+		}
+
+		_, _ = sb.WriteString(`</td></tr>`)
+
+		vctx.Flush()
+	}
+
+	_, _ = sb.WriteString(`</tbody></table>`)
+
+	return sb.String(), nil
+}
@@ -1,5 +1,12 @@
 package gcp
 
+import (
+	"log/slog"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
 type CloudOption func(options *Cloud)
 
 // WithProjectID sets the GCP project id.
@@ -16,6 +23,14 @@ func WithDefaultTableName(defaultTableName string) CloudOption {
 	}
 }
 
+// WithLocation sets the timezone used to bucket costs by day and to calculate the start and end of the query dates.
+// The location must have been loaded with an IANA timezone name, like "America/Los_Angeles". The default is UTC.
+func WithLocation(location *time.Location) CloudOption {
+	return func(options *Cloud) {
+		options.location = location
+	}
+}
+
 // WithResourceTableName sets the bigQuery table name containing resource names. The default value is
 // "billing_export.gcp_billing_export_resource_v1".
 func WithResourceTableName(resourceTableName string) CloudOption {
@@ -23,3 +38,50 @@ func WithResourceTableName(resourceTableName string) CloudOption {
 		options.resourceTableName = resourceTableName
 	}
 }
+
+// WithLogger sets the logger for diagnostics, like query parameters and durations. The default is [slog.Default].
+func WithLogger(logger *slog.Logger) CloudOption {
+	return func(options *Cloud) {
+		options.logger = logger
+	}
+}
+
+// WithMaxBytesScanned sets the maximum number of bytes a query may scan without confirmation. Each query is dry-run
+// first, and returns a [cloudcostexplorer.ConfirmationRequiredError] if the estimate is above it, unless it was
+// confirmed with [cloudcostexplorer.WithQueryConfirmed]. The default is no limit.
+func WithMaxBytesScanned(maxBytesScanned int64) CloudOption {
+	return func(options *Cloud) {
+		options.maxBytesScanned = maxBytesScanned
+	}
+}
+
+// WithMaxBytesBilled sets the maximum bytes billed of each query job, queries above it fail without being billed.
+// The default is the project default.
+func WithMaxBytesBilled(maxBytesBilled int64) CloudOption {
+	return func(options *Cloud) {
+		options.maxBytesBilled = maxBytesBilled
+	}
+}
+
+// WithJobLocation sets the location where the query jobs run, like "US" or "europe-west1". It must match the location
+// of the billing export dataset. The default is to detect it from the tables.
+func WithJobLocation(location string) CloudOption {
+	return func(options *Cloud) {
+		options.jobLocation = location
+	}
+}
+
+// WithJobPriority sets the priority of the query jobs, [bigquery.InteractivePriority] or [bigquery.BatchPriority].
+// The default is interactive.
+func WithJobPriority(priority bigquery.QueryPriority) CloudOption {
+	return func(options *Cloud) {
+		options.jobPriority = priority
+	}
+}
+
+// WithJobLabels sets the labels of the query jobs, so their costs can be identified in the billing data.
+func WithJobLabels(labels map[string]string) CloudOption {
+	return func(options *Cloud) {
+		options.jobLabels = labels
+	}
+}
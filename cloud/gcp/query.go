@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"iter"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,6 +25,7 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 		}
 
 		var useResourceTable bool
+		var useCostLines bool
 
 		fieldsAdd := ""
 		joinAdd := ""
@@ -34,80 +37,99 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 		// }
 		var groupFieldsAdd []string
 
-		nstart, nend := cloudcostexplorer.TimeStartEnd(optns.Start, optns.End)
+		var dateWhere string
+		var queryParameters []bigquery.QueryParameter
 
-		queryParameters := []bigquery.QueryParameter{
-			{Name: "start", Value: nstart.Format(time.RFC3339)},
-			{Name: "end", Value: nend.Format(time.RFC3339)},
+		if optns.InvoiceMonth {
+			// invoice.month is in the YYYYMM format. Usage is invoiced in its month or in an adjacent one, so the usage
+			// time is also limited to allow partition pruning, as the tables are partitioned by it.
+			ustart, uend := cloudcostexplorer.TimeStartEndInLocation(
+				cloudcostexplorer.StartOfMonth(optns.Start).Add(0, -1, 0),
+				cloudcostexplorer.EndingOfMonth(cloudcostexplorer.StartOfMonth(optns.End).Add(0, 1, 0)),
+				c.location)
+
+			dateWhere = "invoice.month >= @invoice_start AND invoice.month <= @invoice_end" +
+				" AND usage_start_time >= @usage_start AND usage_start_time <= @usage_end"
+			queryParameters = append(queryParameters,
+				bigquery.QueryParameter{Name: "invoice_start", Value: optns.Start.Format("YYYYMM")},
+				bigquery.QueryParameter{Name: "invoice_end", Value: optns.End.Format("YYYYMM")},
+				bigquery.QueryParameter{Name: "usage_start", Value: ustart.Format(time.RFC3339)},
+				bigquery.QueryParameter{Name: "usage_end", Value: uend.Format(time.RFC3339)},
+			)
+		} else {
+			nstart, nend := cloudcostexplorer.TimeStartEndInLocation(optns.Start, optns.End, c.location)
+
+			dateWhere = "usage_start_time >= @start AND usage_start_time <= @end"
+			queryParameters = append(queryParameters,
+				bigquery.QueryParameter{Name: "start", Value: nstart.Format(time.RFC3339)},
+				bigquery.QueryParameter{Name: "end", Value: nend.Format(time.RFC3339)},
+			)
 		}
 
-		for _, filter := range optns.Filters {
-			switch filter.ID {
-			case "SERVICE":
-				whereAdd += " AND service.id = @service"
-				queryParameters = append(queryParameters, bigquery.QueryParameter{
-					Name:  "service",
-					Value: filter.Value,
-				})
-			case "REGION":
-				whereAdd += " AND location.region = @region"
-				queryParameters = append(queryParameters, bigquery.QueryParameter{
-					Name:  "region",
-					Value: filter.Value,
-				})
-			case "PROJECT":
-				whereAdd += " AND project.id = @project"
-				queryParameters = append(queryParameters, bigquery.QueryParameter{
-					Name:  "project",
-					Value: filter.Value,
-				})
-			case "SKU":
-				whereAdd += " AND sku.id = @sku"
+		for fidx, filter := range optns.Filters {
+			// each filter uses its own parameter names, so the same filter may be used multiple times.
+			pname := fmt.Sprintf("filter%d", fidx+1)
+
+			if d, ok := dimensions[filter.ID]; ok {
+				useResourceTable = useResourceTable || d.resourceTable
+				useCostLines = useCostLines || d.costLines
+				whereAdd += fmt.Sprintf(" AND %s = @%s", d.idField, pname)
 				queryParameters = append(queryParameters, bigquery.QueryParameter{
-					Name:  "sku",
+					Name:  pname,
 					Value: filter.Value,
 				})
-			case "RESOURCE":
-				whereAdd += " AND resource.global_name = @resource"
+				continue
+			}
+
+			switch filter.ID {
+			case "FOLDER", "ORG":
+				// matches the folder or organization at any ancestor level.
+				whereAdd += fmt.Sprintf(" AND EXISTS(SELECT 1 FROM UNNEST(project.ancestors) a WHERE a.resource_name = @%s)", pname)
 				queryParameters = append(queryParameters, bigquery.QueryParameter{
-					Name:  "resource",
+					Name:  pname,
 					Value: filter.Value,
 				})
-			case "LABEL":
-				useResourceTable = true
-				lkey, lval, _ := strings.Cut(filter.Value, cloudcostexplorer.DataSeparator)
-				joinAdd += fmt.Sprintf(` JOIN UNNEST(labels) as filter_labels ON filter_labels.key = "%s"`, lkey)
-				whereAdd += " AND filter_labels.value = @label_value"
-				queryParameters = append(queryParameters, bigquery.QueryParameter{
-					Name:  "label_value",
-					Value: lval,
-				})
-			case "SYSLABEL":
-				useResourceTable = true
-				lkey, lval, _ := strings.Cut(filter.Value, cloudcostexplorer.DataSeparator)
-				joinAdd += fmt.Sprintf(` JOIN UNNEST(system_labels) as filter_system_labels ON filter_system_labels.key = "%s"`, lkey)
-				whereAdd += " AND filter_system_labels.value = @system_label_value"
-				queryParameters = append(queryParameters, bigquery.QueryParameter{
-					Name:  "system_label_value",
-					Value: lval,
-				})
-			case "TAGS":
-				useResourceTable = true
-				lkey, lval, _ := strings.Cut(filter.Value, cloudcostexplorer.DataSeparator)
-				joinAdd += fmt.Sprintf(` JOIN UNNEST(tags) as filter_tags ON filter_tags.key = "%s"`, lkey)
-				whereAdd += " AND filter_tags.value = @tags_value"
-				queryParameters = append(queryParameters, bigquery.QueryParameter{
-					Name:  "tags_value",
-					Value: lval,
-				})
+			case "LABEL", "SYSLABEL", "TAGS", "PROJECT_LABEL":
+				useResourceTable = useResourceTable || filter.ID != "PROJECT_LABEL"
+				fjoin, fwhere, fparams := labelFilterSQL(labelField(filter.ID), pname, filter.Value)
+				joinAdd += fjoin
+				whereAdd += fwhere
+				queryParameters = append(queryParameters, fparams...)
 			default:
 				yield(cloudcostexplorer.CloudQueryItem{}, fmt.Errorf("unknown filter: %s", filter.ID))
 				return
 			}
 		}
 
+		// EXTRA DATA
+
+		extraDataCtx, extraDataCancel := context.WithCancel(ctx)
+		defer extraDataCancel()
+
+		var labelKeysFuture chan labelKeyItem
+
+		isExtraData := len(optns.Filters) > 0 && optns.ExtraDataCallback != nil
+
+		if isExtraData {
+			// only the filters are used, the group joins are added after this.
+			extraJoinAdd := joinAdd
+			if useCostLines {
+				extraJoinAdd = costLinesJoin() + extraJoinAdd
+			}
+			labelKeysFuture = c.labelKeysFuture(extraDataCtx, extraJoinAdd, dateWhere+whereAdd,
+				slices.Clone(queryParameters), optns.Confirmed)
+		}
+
 		if optns.GroupByDate {
-			fieldsAdd += ", DATE(usage_start_time, 'UTC') as usage_date"
+			if optns.InvoiceMonth {
+				fieldsAdd += ", PARSE_DATE('%Y%m', invoice.month) as usage_date"
+			} else {
+				fieldsAdd += ", DATE(usage_start_time, @timezone) as usage_date"
+				queryParameters = append(queryParameters, bigquery.QueryParameter{
+					Name:  "timezone",
+					Value: c.location.String(),
+				})
+			}
 			groupFieldsAdd = append(groupFieldsAdd, "usage_date")
 		}
 
@@ -121,58 +143,51 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 			kname := fmt.Sprintf("key%d", gidx+1)
 			kdescname := fmt.Sprintf("key%ddesc", gidx+1)
 
+			if d, ok := dimensions[group.ID]; ok {
+				useResourceTable = useResourceTable || d.resourceTable
+				useCostLines = useCostLines || d.costLines
+				fieldsAdd += fmt.Sprintf(", %s AS %s, %s AS %s", d.idField, kname, d.description(), kdescname)
+				groupFieldsAdd = append(groupFieldsAdd, d.groupFields())
+				continue
+			}
+
 			switch group.ID {
-			case "SERVICE":
-				fieldsAdd += fmt.Sprintf(", service.id AS %s, service.description as %s", kname, kdescname)
-				groupFieldsAdd = append(groupFieldsAdd, "service.id, service.description")
-			case "REGION":
-				fieldsAdd += fmt.Sprintf(", location.region AS %s, location.region as %s", kname, kdescname)
-				groupFieldsAdd = append(groupFieldsAdd, "location.region, location.region")
-			case "PROJECT":
-				fieldsAdd += fmt.Sprintf(", project.id as %s, project.name as %s", kname, kdescname)
-				groupFieldsAdd = append(groupFieldsAdd, "project.id, project.name")
-			case "SKU":
-				fieldsAdd += fmt.Sprintf(", sku.id as %s, sku.description as %s", kname, kdescname)
-				groupFieldsAdd = append(groupFieldsAdd, "sku.id, sku.description")
-			case "COSTTYPE":
-				fieldsAdd += fmt.Sprintf(", cost_type as %s, cost_type as %s", kname, kdescname)
-				groupFieldsAdd = append(groupFieldsAdd, "cost_type")
-			case "LABEL":
-				useResourceTable = true
-				if group.Data == "" {
-					fieldsAdd += fmt.Sprintf(", '' as %s, TO_JSON_STRING(labels) as %s", kname, kdescname)
-					groupFieldsAdd = append(groupFieldsAdd, "TO_JSON_STRING(labels)")
-				} else {
-					joinAdd += fmt.Sprintf(` %sJOIN UNNEST(labels) as group_labels ON group_labels.key = "%s"`, labelqueryadd, group.Data)
-					fieldsAdd += fmt.Sprintf(", group_labels.value as %s, group_labels.value as %s", kname, kdescname)
-					groupFieldsAdd = append(groupFieldsAdd, "group_labels.value")
-				}
-			case "SYSLABEL":
-				useResourceTable = true
-				if group.Data == "" {
-					fieldsAdd += fmt.Sprintf(", '' as %s, TO_JSON_STRING(system_labels) as %s", kname, kdescname)
-					groupFieldsAdd = append(groupFieldsAdd, "TO_JSON_STRING(system_labels)")
-				} else {
-					joinAdd += fmt.Sprintf(` %sJOIN UNNEST(system_labels) as group_system_labels ON group_system_labels.key = "%s"`, labelqueryadd, group.Data)
-					fieldsAdd += fmt.Sprintf(", group_system_labels.value as %s, group_system_labels.value as %s", kname, kdescname)
-					groupFieldsAdd = append(groupFieldsAdd, "group_system_labels.value")
+			case "FOLDER", "ORG":
+				ancestorType := "organizations"
+				var level int
+				if group.ID == "FOLDER" {
+					ancestorType = "folders"
+					if group.Data != "" {
+						level, err = strconv.Atoi(group.Data)
+						if err != nil || level < 1 {
+							yield(cloudcostexplorer.CloudQueryItem{}, fmt.Errorf("invalid folder level '%s'", group.Data))
+							return
+						}
+					}
 				}
-			case "TAGS":
-				useResourceTable = true
+				alias := fmt.Sprintf("group%d_ancestor", gidx+1)
+				joinAdd += ancestorJoinSQL(alias, ancestorType, level)
+				fieldsAdd += fmt.Sprintf(", %s.resource_name AS %s, %s.display_name AS %s", alias, kname, alias, kdescname)
+				groupFieldsAdd = append(groupFieldsAdd, fmt.Sprintf("%s.resource_name, %s.display_name", alias, alias))
+			case "LABEL", "SYSLABEL", "TAGS", "PROJECT_LABEL":
+				useResourceTable = useResourceTable || group.ID != "PROJECT_LABEL"
+				field := labelField(group.ID)
 				if group.Data == "" {
-					fieldsAdd += fmt.Sprintf(", '' as %s, TO_JSON_STRING(tags) as %s", kname, kdescname)
-					groupFieldsAdd = append(groupFieldsAdd, "TO_JSON_STRING(tags)")
+					fieldsAdd += fmt.Sprintf(", '' as %s, TO_JSON_STRING(%s) as %s", kname, field, kdescname)
+					groupFieldsAdd = append(groupFieldsAdd, fmt.Sprintf("TO_JSON_STRING(%s)", field))
 				} else {
-					joinAdd += fmt.Sprintf(` %sJOIN UNNEST(tags) as group_tags ON group_tags.key = "%s"`, labelqueryadd, group.Data)
-					fieldsAdd += fmt.Sprintf(", group_tags.value as %s, group_tags.value as %s", kname, kdescname)
-					groupFieldsAdd = append(groupFieldsAdd, "group_tags.value")
+					pname := fmt.Sprintf("group%d_key", gidx+1)
+					alias := fmt.Sprintf("group%d_%s", gidx+1, labelAlias(field))
+					joinAdd += fmt.Sprintf(` %sJOIN UNNEST(%s) as %s ON %s.key = @%s`, labelqueryadd, field, alias, alias, pname)
+					fieldsAdd += fmt.Sprintf(", %s.value as %s, %s.value as %s", alias, kname, alias, kdescname)
+					groupFieldsAdd = append(groupFieldsAdd, fmt.Sprintf("%s.value", alias))
+					queryParameters = append(queryParameters, bigquery.QueryParameter{
+						Name:  pname,
+						Value: group.Data,
+					})
 				}
-			case "RESOURCE":
-				useResourceTable = true
-				fieldsAdd += fmt.Sprintf(", resource.global_name as %s, resource.name as %s", kname, kdescname)
-				groupFieldsAdd = append(groupFieldsAdd, "resource.global_name, resource.name")
 			default:
-				yield(cloudcostexplorer.CloudQueryItem{}, fmt.Errorf("unknown filter: %s", fmt.Errorf("unknown group: %s", group)))
+				yield(cloudcostexplorer.CloudQueryItem{}, fmt.Errorf("unknown group: %s", group.ID))
 				return
 			}
 		}
@@ -182,27 +197,42 @@ func (c *Cloud) Query(ctx context.Context, options ...cloudcostexplorer.QueryOpt
 			tableName = c.resourceTableName
 		}
 
-		query := fmt.Sprintf(`select
-    SUM(cost)
+		totalFields := `SUM(cost)
     + SUM(IFNULL((SELECT SUM(c.amount)
                   FROM UNNEST(credits) c), 0))
-    AS total %s
+    AS total,
+    SUM(IFNULL((SELECT SUM(c.amount)
+                  FROM UNNEST(credits) c), 0))
+    AS credits`
+		if useCostLines {
+			// split each row into one line for the cost and one line for each credit, so credits can be grouped and
+			// filtered without duplicating the cost.
+			joinAdd = costLinesJoin() + joinAdd
+			totalFields = fmt.Sprintf(`SUM(cost_lines.amount) AS total,
+    SUM(IF(cost_lines.type = '%s', 0, cost_lines.amount)) AS credits`, costLineType)
+		}
+
+		query := fmt.Sprintf(`select
+    %s %s
 FROM 
 	%s
 	%s
 WHERE
-    usage_start_time >= @start AND usage_start_time <= @end
+    %s
 	%s
 GROUP BY %s
 %s
-`, fieldsAdd, tableName, joinAdd, whereAdd, strings.Join(groupFieldsAdd, ", "), havingAdd)
+`, totalFields, fieldsAdd, tableName, joinAdd, dateWhere, whereAdd, strings.Join(groupFieldsAdd, ", "), havingAdd)
 
-		servicesQuery := c.bigQueryClient.Query(query)
+		c.logger.DebugContext(ctx, "BigQuery query",
+			"start", optns.Start, "end", optns.End, "groups", optns.Groups, "filters", optns.Filters,
+			"table", tableName, "sql", query, "parameters", queryParameters)
+		queryStart := time.Now()
+		var queryRows int
 
-		servicesQuery.Parameters = queryParameters
-
-		servicesIter, err := servicesQuery.Read(ctx)
+		servicesIter, err := c.runQuery(ctx, query, queryParameters, optns.Confirmed, optns.QueryInfoCallback)
 		if err != nil {
+			c.logger.ErrorContext(ctx, "BigQuery query failed", "error", err, "duration", time.Since(queryStart))
 			yield(cloudcostexplorer.CloudQueryItem{}, fmt.Errorf("error querying BigQuery: %w", err))
 			return
 		}
@@ -214,11 +244,14 @@ GROUP BY %s
 				break
 			}
 			if err != nil {
+				c.logger.ErrorContext(ctx, "BigQuery query failed", "error", err, "duration", time.Since(queryStart))
 				yield(cloudcostexplorer.CloudQueryItem{}, fmt.Errorf("error iterating BigQuery: %w", err))
 				return
 			}
+			queryRows++
 
 			cost := row["total"].(float64)
+			credits := row["credits"].(float64)
 
 			var itemKeys []cloudcostexplorer.ItemKey
 			for groupIdx, group := range optns.Groups {
@@ -242,7 +275,7 @@ GROUP BY %s
 				}
 
 				switch group.ID {
-				case "LABEL", "SYSLABEL", "TAGS":
+				case "LABEL", "SYSLABEL", "TAGS", "PROJECT_LABEL":
 					// if there is a group filter only one value is shown
 					if group.Data == "" {
 						key.Value = NewLabelValue(group.ID, fmt.Sprint(key.Value))
@@ -251,6 +284,10 @@ GROUP BY %s
 					if !keyWasBlank && keyValue == "" {
 						key.Value = key.ID
 					}
+				case "CREDIT_TYPE":
+					if !keyWasBlank {
+						key.Value = creditTypeTitle(key.ID)
+					}
 				}
 
 				itemKeys = append(itemKeys, key)
@@ -266,16 +303,80 @@ GROUP BY %s
 			}
 
 			if !yield(cloudcostexplorer.CloudQueryItem{
-				Date:  itemDate,
-				Keys:  itemKeys,
-				Value: cost,
+				Date:    itemDate,
+				Keys:    itemKeys,
+				Value:   cost,
+				Credits: credits,
 			}, nil) {
 				return
 			}
 		}
+
+		c.logger.InfoContext(ctx, "BigQuery query finished",
+			"start", optns.Start, "end", optns.End, "rows", queryRows, "total_rows", servicesIter.TotalRows,
+			"duration", time.Since(queryStart))
+
+		if isExtraData {
+			ed := &extraDataLabels{
+				data: map[string]*extraDataLabel{},
+			}
+			for lk := range labelKeysFuture {
+				if lk.err != nil {
+					ed.err = fmt.Errorf("error getting labels: %w", lk.err)
+					break
+				}
+				ed.add(lk.value)
+			}
+			optns.ExtraDataCallback(ed)
+		}
 	}
 }
 
-func (c *Cloud) QueryExtraOutput(ctx context.Context, extraData []cloudcostexplorer.QueryExtraData) cloudcostexplorer.QueryExtraOutput {
-	return nil
+// labelField returns the billing export field of a label parameter.
+func labelField(parameterID string) string {
+	switch parameterID {
+	case "SYSLABEL":
+		return "system_labels"
+	case "TAGS":
+		return "tags"
+	case "PROJECT_LABEL":
+		return "project.labels"
+	default:
+		return "labels"
+	}
+}
+
+// ancestorJoinSQL returns a join which adds a project ancestor of the passed type ("folders" or "organizations") as
+// "alias", with at most one row so costs are not duplicated. The ancestors are ordered from the project to the
+// organization. If level > 0, the ancestor with this level counting from the organization is used (1 is the
+// top-level folder), otherwise the nearest one.
+func ancestorJoinSQL(alias string, ancestorType string, level int) string {
+	order := "ASC"
+	offset := 0
+	if level > 0 {
+		order = "DESC"
+		offset = level - 1
+	}
+	return fmt.Sprintf(` LEFT JOIN UNNEST(ARRAY(SELECT AS STRUCT a.resource_name, a.display_name
+		FROM UNNEST(project.ancestors) a WITH OFFSET ao
+		WHERE STARTS_WITH(a.resource_name, '%s/') ORDER BY ao %s LIMIT 1 OFFSET %d)) AS %s ON TRUE`,
+		ancestorType, order, offset, alias)
+}
+
+// labelAlias returns a label field name which can be used as part of an alias.
+func labelAlias(field string) string {
+	return strings.ReplaceAll(field, ".", "_")
+}
+
+// labelFilterSQL returns the join and where clauses to filter a label field by a "key|value" filter value, using
+// bound parameters and a join alias derived from "paramName".
+func labelFilterSQL(field string, paramName string, filterValue string) (string, string, []bigquery.QueryParameter) {
+	lkey, lval, _ := strings.Cut(filterValue, cloudcostexplorer.DataSeparator)
+	alias := fmt.Sprintf("%s_%s", paramName, labelAlias(field))
+	return fmt.Sprintf(` JOIN UNNEST(%s) as %s ON %s.key = @%s_key`, field, alias, alias, paramName),
+		fmt.Sprintf(" AND %s.value = @%s_value", alias, paramName),
+		[]bigquery.QueryParameter{
+			{Name: paramName + "_key", Value: lkey},
+			{Name: paramName + "_value", Value: lval},
+		}
 }
@@ -11,6 +11,46 @@ type parameterValues struct {
 	values map[string]string
 }
 
+// costLineType is the credit type used for the cost line when splitting costs and credits.
+const costLineType = "COST"
+
+// costLinesJoin returns a join which splits each row into one line for the cost and one line for each credit, with
+// the "cost_lines" alias.
+func costLinesJoin() string {
+	return fmt.Sprintf(` CROSS JOIN UNNEST(ARRAY_CONCAT(
+		[STRUCT('%s' AS type, '%s' AS name, cost AS amount)],
+		ARRAY(SELECT AS STRUCT c.type, c.name, c.amount FROM UNNEST(credits) c))) AS cost_lines`,
+		costLineType, creditTypeTitle(costLineType))
+}
+
+// creditTypeTitle returns a title for a billing export credit type.
+func creditTypeTitle(creditType string) string {
+	switch creditType {
+	case costLineType:
+		return "Cost before credits"
+	case "SUSTAINED_USAGE_DISCOUNT":
+		return "Sustained use discount"
+	case "COMMITTED_USAGE_DISCOUNT":
+		return "Committed use discount (resource-based)"
+	case "COMMITTED_USAGE_DISCOUNT_DOLLAR_BASE":
+		return "Committed use discount (spend-based)"
+	case "DISCOUNT":
+		return "Discount"
+	case "FREE_TIER":
+		return "Free tier"
+	case "PROMOTION":
+		return "Promotion"
+	case "RESELLER_MARGIN":
+		return "Reseller margin"
+	case "SUBSCRIPTION_BENEFIT":
+		return "Subscription benefit"
+	case "FEE_UTILIZATION_OFFSET":
+		return "Fee utilization offset"
+	default:
+		return creditType
+	}
+}
+
 // bigQueryStringValue returns the string value of a bigQuery result field.
 func bigQueryStringValue(row map[string]bigquery.Value, fieldName string) string {
 	v, ok := row[fieldName]
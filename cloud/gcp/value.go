@@ -48,7 +48,7 @@ func (o *LabelValue) Output(ctx context.Context, vctx cloudcostexplorer.ValueCon
 		_, _ = sb.WriteString(fmt.Sprintf(`<tr><td><strong><a href="%s">%s</a></strong></td><td><a href="%s">%s</a></td></tr>`,
 			uq.Clone().Set(vctx.GroupParamName(), fmt.Sprintf("%s%s%s", o.paramName, cloudcostexplorer.DataSeparator, label.Key)),
 			label.Key,
-			uq.Clone().Set(vctx.FilterParamName(o.paramName), fmt.Sprintf("%s%s%s", label.Key, cloudcostexplorer.DataSeparator, label.Value)),
+			uq.Clone().Add(vctx.FilterParamName(o.paramName), fmt.Sprintf("%s%s%s", label.Key, cloudcostexplorer.DataSeparator, label.Value)),
 			label.Value))
 	}
 	_, _ = sb.WriteString(`</tbody></table>`)
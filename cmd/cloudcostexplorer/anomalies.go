@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/dustin/go-humanize"
+	"github.com/invzhi/timex"
+	"github.com/rrgmc/cloudcostexplorer"
+	aws2 "github.com/rrgmc/cloudcostexplorer/cloud/aws"
+	ui2 "github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
+)
+
+// handlerAnomalies shows the AWS Cost Anomaly Detection results.
+func handlerAnomalies(item string, cloud *aws2.Cloud, location *time.Location) http.Handler {
+	return ui2.HTTPHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		rootPath := pagePath("anomalies", item)
+
+		uq := cloudcostexplorer.NewURLQuery(rootPath)
+
+		// parameters
+		var query aws2.AnomalyQuery
+		if value, paramExists := HTTPQueryStringValue(r, "monitor", ""); paramExists && value != "" {
+			query.MonitorArn = value
+			uq.Set("monitor", value)
+		}
+		if value, paramExists := HTTPQueryStringValue(r, "feedback", ""); paramExists && value != "" {
+			query.Feedback = types.AnomalyFeedbackType(value)
+			if !slices.Contains(query.Feedback.Values(), query.Feedback) {
+				return fmt.Errorf("invalid feedback '%s'", value)
+			}
+			uq.Set("feedback", value)
+		}
+
+		if period := r.URL.Query().Get("period"); period != "" {
+			uq.Set("period", period)
+		}
+		periodList, periodDesc, err := ParsePeriod(r, location)
+		if err != nil {
+			return err
+		}
+		ok, start, end := periodList[0].Range()
+		if !ok {
+			return fmt.Errorf("invalid period")
+		}
+		query.Start, query.End = start, end
+
+		report := cloud.Anomalies(r.Context(), query)
+
+		out := ui2.NewHTTPOutput(w)
+
+		out.DocBegin(fmt.Sprintf("%s - Anomalies - CloudCostExplorer", item))
+
+		out.NavBegin(rootPath)
+		out.NavMenuBegin()
+
+		out.NavDropdownBegin("Monitor")
+		out.NavDropdownItem("All", uq.Clone().Remove("monitor").String())
+		for _, monitor := range report.Monitors {
+			out.NavDropdownItem(monitor.Name, uq.Clone().Set("monitor", monitor.Arn).String())
+		}
+		out.NavDropdownEnd()
+
+		out.NavDropdownBegin("Feedback")
+		out.NavDropdownItem("All", uq.Clone().Remove("feedback").String())
+		for _, value := range query.Feedback.Values() {
+			out.NavDropdownItem(enumTitle(string(value)), uq.Clone().Set("feedback", string(value)).String())
+		}
+		out.NavDropdownEnd()
+
+		out.NavDropdownBegin("Period")
+		out.NavDropdownItem("14 days", uq.Clone().Set("period", "d14").String())
+		out.NavDropdownItem("30 days", uq.Clone().Set("period", "d30").String())
+		out.NavDropdownItem("3 months", uq.Clone().Set("period", "m3").String())
+		out.NavDropdownItem("Month to date", uq.Clone().Set("period", "MTD").String())
+		out.NavDropdownItem("Last complete month", uq.Clone().Set("period", "LM").String())
+		out.NavDropdownEnd()
+
+		writePagesMenu(out, item, cloud)
+
+		out.NavMenuEnd()
+
+		out.NavTextCustom(`<span class="badge bg-secondary">Period</span>`, periodDesc)
+		if query.MonitorArn != "" {
+			monitorTitle := query.MonitorArn
+			for _, monitor := range report.Monitors {
+				if monitor.Arn == query.MonitorArn {
+					monitorTitle = monitor.Name
+				}
+			}
+			out.NavTextCustom(fmt.Sprintf(`<span class="badge bg-secondary">Monitor <a href="%s"><i class="bi bi-trash text-white"></i></a></span>`,
+				uq.Clone().Remove("monitor")),
+				cloudcostexplorer.EllipticalTruncate(monitorTitle, 32))
+		}
+		if query.Feedback != "" {
+			out.NavTextCustom(fmt.Sprintf(`<span class="badge bg-secondary">Feedback <a href="%s"><i class="bi bi-trash text-white"></i></a></span>`,
+				uq.Clone().Remove("feedback")),
+				enumTitle(string(query.Feedback)))
+		}
+
+		out.NavEnd()
+
+		out.BodyBegin()
+
+		if report.MonitorsErr != nil {
+			out.Writef(`<p>error: %s</p>`, report.MonitorsErr.Error())
+		}
+
+		out.Writeln(`<h3>Anomalies</h3>`)
+		writeAnomalies(out, item, end, report)
+
+		out.BodyEnd()
+
+		writePageFooter(out, r, cloud)
+
+		out.DocEnd()
+
+		return nil
+	})
+}
+
+// writeAnomalies outputs a table of anomalies, with links to the cost explorer filtered by each root cause.
+func writeAnomalies(out *ui2.HTTPOutput, item string, periodEnd timex.Date, report *aws2.AnomalyReport) {
+	if report.AnomaliesErr != nil {
+		out.Writef(`<p>error: %s</p>`, report.AnomaliesErr.Error())
+		return
+	}
+	if len(report.Anomalies) == 0 {
+		out.Writeln(`<p>No anomalies.</p>`)
+		return
+	}
+
+	out.Writeln(`<div class="table-responsive"><table class="table table-striped table-bordered table-sm">`)
+	out.Writeln(`<thead><tr><th>Start</th><th>End</th><th>Monitor</th><th>Impact</th><th>Impact %</th><th>Actual</th>
+<th>Expected</th><th>Max score</th><th>Feedback</th><th>Root causes</th></tr></thead>`)
+	out.Writeln(`<tbody>`)
+	for _, anomaly := range report.Anomalies {
+		monitor := anomaly.MonitorName
+		if monitor == "" {
+			monitor = anomaly.MonitorArn
+		}
+		if anomaly.DimensionValue != "" {
+			monitor = fmt.Sprintf("%s<br><small>%s</small>", monitor, anomaly.DimensionValue)
+		}
+		anomalyEnd := anomaly.End
+		if anomalyEnd == "" {
+			anomalyEnd = "ongoing"
+		}
+		feedback := "-"
+		if anomaly.Feedback != "" {
+			feedback = enumTitle(string(anomaly.Feedback))
+		}
+
+		out.Writeln(`<tr>`)
+		out.Writef(`<td>%s</td>`, anomaly.Start)
+		out.Writef(`<td>%s</td>`, anomalyEnd)
+		out.Writef(`<td>%s</td>`, monitor)
+		out.Writef(`<td align="right" class="text-danger"><strong>%s</strong></td>`, cloudcostexplorer.FormatMoney(anomaly.TotalImpact))
+		out.Writef(`<td align="right">%s%%</td>`, humanize.CommafWithDigits(anomaly.ImpactPercentage, 2))
+		out.Writef(`<td align="right">%s</td>`, cloudcostexplorer.FormatMoney(anomaly.ActualSpend))
+		out.Writef(`<td align="right">%s</td>`, cloudcostexplorer.FormatMoney(anomaly.ExpectedSpend))
+		out.Writef(`<td align="right">%s</td>`, humanize.CommafWithDigits(anomaly.MaxScore, 2))
+		out.Writef(`<td>%s</td>`, feedback)
+		out.Writeln(`<td><ul class="list-unstyled mb-0">`)
+		for _, rootCause := range anomaly.RootCauses {
+			out.Writef(`<li><a href="%s">%s</a></li>`, anomalyRootCauseQuery(item, periodEnd, anomaly, rootCause),
+				anomalyRootCauseTitle(rootCause))
+		}
+		out.Writeln(`</ul></td>`)
+		out.Writeln(`</tr>`)
+	}
+	out.Writeln(`</tbody></table></div>`)
+}
+
+// anomalyRootCauseTitle returns a description of the root cause with all non-blank values.
+func anomalyRootCauseTitle(rootCause aws2.AnomalyRootCause) string {
+	var ret []string
+	account := rootCause.LinkedAccount
+	if rootCause.LinkedAccountName != "" {
+		account = fmt.Sprintf("%s (%s)", rootCause.LinkedAccountName, rootCause.LinkedAccount)
+	}
+	for _, value := range []string{rootCause.Service, account, rootCause.Region, rootCause.UsageType} {
+		if value != "" {
+			ret = append(ret, value)
+		}
+	}
+	return strings.Join(ret, " / ")
+}
+
+// anomalyRootCauseQuery returns the cost explorer query filtered by the root cause values during the anomaly
+// period. Ongoing anomalies use the end of the selected period.
+func anomalyRootCauseQuery(item string, periodEnd timex.Date, anomaly *aws2.Anomaly,
+	rootCause aws2.AnomalyRootCause) *cloudcostexplorer.URLQuery {
+	anomalyEnd := anomaly.End
+	if anomalyEnd == "" {
+		anomalyEnd = periodEnd.String()
+	}
+	ret := cloudcostexplorer.NewURLQuery(pagePath("costexplorer", item)).
+		Set("period", fmt.Sprintf("T%s|%s", anomaly.Start, anomalyEnd)).
+		Set("group1", "SERVICE")
+	filters := rootCause.Filters()
+	for id, value := range filters {
+		ret.Set(fmt.Sprintf("f%s", id), value)
+	}
+	if _, ok := filters["SERVICE"]; ok {
+		ret.Set("group1", "USAGE_TYPE")
+	}
+	return ret
+}
+
+// anomaliesResult is the result of [detectedAnomaliesFuture], with the anomalies page query of the period.
+type anomaliesResult struct {
+	anomalies []*aws2.Anomaly
+	query     *cloudcostexplorer.URLQuery
+	err       error
+}
+
+// detectedAnomaliesFuture gets the anomalies detected in the period in the background.
+func detectedAnomaliesFuture(ctx context.Context, cloud *aws2.Cloud, item string, start, end timex.Date) <-chan anomaliesResult {
+	ret := make(chan anomaliesResult, 1)
+	go func() {
+		defer close(ret)
+		anomalies, err := cloud.DetectedAnomalies(ctx, aws2.AnomalyQuery{Start: start, End: end})
+		ret <- anomaliesResult{
+			anomalies: anomalies,
+			query: cloudcostexplorer.NewURLQuery(pagePath("anomalies", item)).
+				Set("period", fmt.Sprintf("T%s|%s", start, end)),
+			err: err,
+		}
+	}()
+	return ret
+}
+
+// anomalyMarkers matches the cost explorer items with the anomalies which have them as root cause.
+type anomalyMarkers struct {
+	anomalies      []*aws2.Anomaly
+	anomaliesQuery *cloudcostexplorer.URLQuery
+	filters        map[string]string // values of the marker parameters filtered by the query.
+}
+
+// anomalyMarkerParameters are the parameters which can be matched with anomaly root causes.
+var anomalyMarkerParameters = []string{"LINKED_ACCOUNT", "SERVICE", "REGION", "USAGE_TYPE"}
+
+func newAnomalyMarkers(anomalies []*aws2.Anomaly, anomaliesQuery *cloudcostexplorer.URLQuery,
+	filters []cloudcostexplorer.QueryFilter) *anomalyMarkers {
+	ret := &anomalyMarkers{
+		anomalies:      anomalies,
+		anomaliesQuery: anomaliesQuery,
+		filters:        map[string]string{},
+	}
+	for _, filter := range filters {
+		if slices.Contains(anomalyMarkerParameters, filter.ID) {
+			ret.filters[filter.ID] = filter.Value
+		}
+	}
+	return ret
+}
+
+// match returns the anomalies with a root cause matching the item keys. All the non-blank root cause fields which
+// are grouped or filtered must be equal to the item values, and at least one of them must be grouped.
+func (m *anomalyMarkers) match(groups []cloudcostexplorer.QueryResultGroup, keys []cloudcostexplorer.ItemKey) []*aws2.Anomaly {
+	values := maps.Clone(m.filters)
+	grouped := map[string]bool{}
+	for groupIdx, key := range keys {
+		if id := groups[groupIdx].ID; slices.Contains(anomalyMarkerParameters, id) {
+			values[id] = key.ID
+			grouped[id] = true
+		}
+	}
+	if len(grouped) == 0 {
+		return nil
+	}
+
+	var ret []*aws2.Anomaly
+	for _, anomaly := range m.anomalies {
+		if slices.ContainsFunc(anomaly.RootCauses, func(rootCause aws2.AnomalyRootCause) bool {
+			isGrouped := false
+			for id, value := range rootCause.Filters() {
+				itemValue, ok := values[id]
+				if !ok {
+					continue
+				}
+				if itemValue != value {
+					return false
+				}
+				isGrouped = isGrouped || grouped[id]
+			}
+			return isGrouped
+		}) {
+			ret = append(ret, anomaly)
+		}
+	}
+	return ret
+}
+
+// output returns a marker linking to the anomalies page if the item keys match an anomaly root cause.
+func (m *anomalyMarkers) output(groups []cloudcostexplorer.QueryResultGroup, keys []cloudcostexplorer.ItemKey) string {
+	if m == nil {
+		return ""
+	}
+	anomalies := m.match(groups, keys)
+	if len(anomalies) == 0 {
+		return ""
+	}
+	var titles []string
+	for _, anomaly := range anomalies {
+		titles = append(titles, fmt.Sprintf("%s: %s impact", anomaly.Start, cloudcostexplorer.FormatMoney(anomaly.TotalImpact)))
+	}
+	return fmt.Sprintf(`&nbsp;<a title="Cost anomaly (%s)" class="link-danger" href="%s"><i class="bi bi-exclamation-triangle"></i></a>`,
+		html.EscapeString(strings.Join(titles, ", ")),
+		m.anomaliesQuery)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	budgettypes "github.com/aws/aws-sdk-go-v2/service/budgets/types"
+	"github.com/dustin/go-humanize"
+	"github.com/rrgmc/cloudcostexplorer"
+	aws2 "github.com/rrgmc/cloudcostexplorer/cloud/aws"
+	ui2 "github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
+)
+
+// handlerBudgets shows the AWS Budgets status, with the actual and forecasted spend against each budget limit.
+func handlerBudgets(item string, cloud *aws2.Cloud) http.Handler {
+	return ui2.HTTPHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		rootPath := pagePath("budgets", item)
+
+		budgets, budgetsErr := cloud.Budgets(r.Context())
+
+		out := ui2.NewHTTPOutput(w)
+
+		out.DocBegin(fmt.Sprintf("%s - Budgets - CloudCostExplorer", item))
+
+		out.NavBegin(rootPath)
+		out.NavMenuBegin()
+
+		writePagesMenu(out, item, cloud)
+
+		out.NavMenuEnd()
+		out.NavEnd()
+
+		out.BodyBegin()
+
+		out.Writeln(`<h3>Budgets</h3>`)
+		if budgetsErr != nil {
+			out.Writef(`<p>error: %s</p>`, budgetsErr.Error())
+		} else {
+			writeBudgets(out, item, cloud, budgets)
+		}
+
+		out.BodyEnd()
+
+		writePageFooter(out, r, cloud)
+
+		out.DocEnd()
+
+		return nil
+	})
+}
+
+// writeBudgets outputs a table of budgets, each one linking to the cost explorer with the budget filters.
+func writeBudgets(out *ui2.HTTPOutput, item string, cloud *aws2.Cloud, budgets []*aws2.Budget) {
+	if len(budgets) == 0 {
+		out.Writeln(`<p>No budgets.</p>`)
+		return
+	}
+
+	percentageClass := func(value float64) string {
+		switch {
+		case value >= 100:
+			return "text-danger"
+		case value >= 80:
+			return "text-warning"
+		default:
+			return "text-success"
+		}
+	}
+	formatAmount := func(value float64, unit string) string {
+		if unit == "USD" {
+			return cloudcostexplorer.FormatMoney(value)
+		}
+		return fmt.Sprintf("%s %s", humanize.CommafWithDigits(value, 2), unit)
+	}
+
+	out.Writeln(`<div class="table-responsive"><table class="table table-striped table-bordered table-sm">`)
+	out.Writeln(`<thead><tr><th>Budget</th><th>Type</th><th>Period</th><th>Limit</th><th>Actual</th><th>Actual %</th>
+<th>Forecast</th><th>Forecast %</th><th>Filters</th></tr></thead>`)
+	out.Writeln(`<tbody>`)
+	for _, budget := range budgets {
+		var filters []string
+		for _, filter := range budget.Filters {
+			filterTitle := filter.ID
+			if parameter, ok := cloud.Parameters().FindById(filter.ID); ok {
+				filterTitle = parameter.Name
+			}
+			filters = append(filters, fmt.Sprintf("%s: %s", filterTitle, cloud.ParameterTitle(filter.ID, filter.Value)))
+		}
+		for _, name := range budget.UnsupportedFilters {
+			filters = append(filters, fmt.Sprintf(`<span class="text-muted" title="not supported by the cost explorer">%s</span>`, name))
+		}
+
+		out.Writeln(`<tr>`)
+		if query, err := budgetQuery(item, budget); err != nil {
+			out.Writef(`<td><span title="%s">%s</span></td>`, html.EscapeString(err.Error()), budget.Name)
+		} else {
+			out.Writef(`<td><a href="%s">%s</a></td>`, query, budget.Name)
+		}
+		out.Writef(`<td>%s</td>`, enumTitle(string(budget.Type)))
+		out.Writef(`<td>%s</td>`, enumTitle(string(budget.TimeUnit)))
+		out.Writef(`<td align="right">%s</td>`, formatAmount(budget.Limit, budget.Unit))
+		out.Writef(`<td align="right">%s</td>`, formatAmount(budget.Actual, budget.Unit))
+		out.Writef(`<td align="right" class="%s"><strong>%s%%</strong></td>`, percentageClass(budget.ActualPercentage()),
+			humanize.CommafWithDigits(budget.ActualPercentage(), 2))
+		out.Writef(`<td align="right">%s</td>`, formatAmount(budget.Forecast, budget.Unit))
+		out.Writef(`<td align="right" class="%s">%s%%</td>`, percentageClass(budget.ForecastPercentage()),
+			humanize.CommafWithDigits(budget.ForecastPercentage(), 2))
+		out.Writef(`<td>%s</td>`, strings.Join(filters, "<br>"))
+		out.Writeln(`</tr>`)
+	}
+	out.Writeln(`</tbody></table></div>`)
+}
+
+// budgetQuery returns the cost explorer query with the budget filters for the current budget period. Returns an
+// error if the cost explorer can't show the same costs as the budget.
+func budgetQuery(item string, budget *aws2.Budget) (*cloudcostexplorer.URLQuery, error) {
+	if budget.Type != budgettypes.BudgetTypeCost {
+		return nil, fmt.Errorf("%s budgets are not shown in the cost explorer", strings.ToLower(enumTitle(string(budget.Type))))
+	}
+	if len(budget.UnsupportedFilters) > 0 {
+		return nil, fmt.Errorf("budget filters not supported by the cost explorer: %s",
+			strings.Join(budget.UnsupportedFilters, ", "))
+	}
+
+	ret := cloudcostexplorer.NewURLQuery(pagePath("costexplorer", item))
+	switch budget.TimeUnit {
+	case budgettypes.TimeUnitDaily:
+		ret.Set("period", "d1")
+	case budgettypes.TimeUnitQuarterly:
+		ret.Set("period", "QTD")
+	case budgettypes.TimeUnitAnnually:
+		ret.Set("period", "YTD")
+	default:
+		ret.Set("period", "MTD")
+	}
+	for _, filter := range budget.Filters {
+		ret.Add(fmt.Sprintf("f%s", filter.ID), filter.Value)
+	}
+	return ret, nil
+}
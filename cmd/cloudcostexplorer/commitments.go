@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rrgmc/cloudcostexplorer"
+	aws2 "github.com/rrgmc/cloudcostexplorer/cloud/aws"
+	ui2 "github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
+)
+
+// handlerCommitments shows the AWS Savings Plans and Reserved Instances utilization and coverage.
+func handlerCommitments(item string, cloud *aws2.Cloud, location *time.Location) http.Handler {
+	return ui2.HTTPHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		rootPath := pagePath("commitments", item)
+
+		uq := cloudcostexplorer.NewURLQuery(rootPath)
+
+		// parameters
+		groupBy := aws2.CommitmentDimensionService
+		if groupValue, paramExists := HTTPQueryStringValue(r, "group", ""); paramExists {
+			groupBy = aws2.CommitmentDimension(groupValue)
+			if !slices.Contains(aws2.CommitmentDimensions, groupBy) {
+				return fmt.Errorf("invalid group '%s'", groupValue)
+			}
+			uq.Set("group", groupValue)
+		}
+
+		filters := map[aws2.CommitmentDimension]string{}
+		for _, dim := range aws2.CommitmentDimensions {
+			queryParamName := fmt.Sprintf("f%s", dim)
+			if filterValue, paramExists := HTTPQueryStringValue(r, queryParamName, ""); paramExists && filterValue != "" {
+				filters[dim] = filterValue
+				uq.Set(queryParamName, filterValue)
+			}
+		}
+
+		if period := r.URL.Query().Get("period"); period != "" {
+			uq.Set("period", period)
+		}
+		periodList, periodDesc, err := ParsePeriod(r, location)
+		if err != nil {
+			return err
+		}
+		if len(periodList) != 1 || len(periodList[0].Periods) != 1 {
+			return errors.New("comparison periods are not supported by the commitments report")
+		}
+		ok, start, end := periodList[0].Range()
+		if !ok {
+			return fmt.Errorf("invalid period")
+		}
+
+		report := cloud.Commitments(r.Context(), aws2.CommitmentQuery{
+			Start:   start,
+			End:     end,
+			GroupBy: groupBy,
+			Filters: filters,
+		})
+
+		out := ui2.NewHTTPOutput(w)
+
+		out.DocBegin(fmt.Sprintf("%s - Commitments - CloudCostExplorer", item))
+
+		out.NavBegin(rootPath)
+		out.NavMenuBegin()
+
+		out.NavDropdownBegin("Group by")
+		for _, dim := range aws2.CommitmentDimensions {
+			out.NavDropdownItem(dim.Title(), uq.Clone().Set("group", string(dim)).String())
+		}
+		out.NavDropdownEnd()
+
+		out.NavDropdownBegin("Period")
+		out.NavDropdownItem("14 days", uq.Clone().Set("period", "d14").String())
+		out.NavDropdownItem("1 month", uq.Clone().Set("period", "m1").String())
+		out.NavDropdownItem("3 months", uq.Clone().Set("period", "m3").String())
+		out.NavDropdownItem("6 months", uq.Clone().Set("period", "m6").String())
+		out.NavDropdownItem("12 months", uq.Clone().Set("period", "m12").String())
+		out.NavDropdownItem("Month to date", uq.Clone().Set("period", "MTD").String())
+		out.NavDropdownItem("Last complete month", uq.Clone().Set("period", "LM").String())
+		out.NavDropdownItem("Last complete quarter", uq.Clone().Set("period", "LQ").String())
+		out.NavDropdownEnd()
+
+		writePagesMenu(out, item, cloud)
+
+		out.NavMenuEnd()
+
+		out.NavTextCustom(`<span class="badge bg-secondary">Period</span>`, periodDesc)
+		out.NavTextCustom(`<span class="badge bg-secondary">Group</span>`, groupBy.Title())
+		for _, dim := range aws2.CommitmentDimensions {
+			filterValue, ok := filters[dim]
+			if !ok {
+				continue
+			}
+			out.NavTextCustom(fmt.Sprintf(`<span class="badge bg-secondary">%s <a href="%s"><i class="bi bi-trash text-white"></i></a></span>`,
+				dim.Title(),
+				uq.Clone().Remove(fmt.Sprintf("f%s", dim))),
+				cloudcostexplorer.EllipticalTruncate(filterValue, 32))
+		}
+
+		out.NavEnd()
+
+		out.BodyBegin()
+
+		formatHours := func(value float64) string {
+			return fmt.Sprintf("%s h", humanize.CommafWithDigits(value, 0))
+		}
+
+		out.Writeln(`<h3>Savings Plans</h3>`)
+		writeCommitmentUtilization(out, "Commitment", cloudcostexplorer.FormatMoney, report.SavingsPlansUtilization)
+		writeCommitmentCoverage(out, uq, groupBy, true, cloudcostexplorer.FormatMoney, report.SavingsPlansCoverage)
+
+		out.Writeln(`<h3>Reserved Instances</h3>`)
+		writeCommitmentUtilization(out, "Purchased", formatHours, report.ReservationUtilization)
+		// reservation coverage groups by instance type instead of instance family, which can't be used as filter.
+		writeCommitmentCoverage(out, uq, groupBy, groupBy != aws2.CommitmentDimensionInstanceFamily, formatHours,
+			report.ReservationCoverage)
+
+		out.BodyEnd()
+
+		writePageFooter(out, r, cloud)
+
+		out.DocEnd()
+
+		return nil
+	})
+}
+
+// writeCommitmentUtilization outputs the commitment utilization totals.
+func writeCommitmentUtilization(out *ui2.HTTPOutput, totalTitle string, format func(float64) string,
+	utilization aws2.CommitmentUtilization) {
+	out.Writeln(`<h5>Utilization</h5>`)
+	if utilization.Err != nil {
+		out.Writef(`<p>error: %s</p>`, utilization.Err.Error())
+		return
+	}
+	writeIgnoredCommitmentFilters(out, utilization.IgnoredFilters)
+
+	utilizationClass := "text-success"
+	if utilization.UtilizationPercentage < 90 {
+		utilizationClass = "text-danger"
+	}
+
+	out.Writeln(`<table class="table table-striped table-bordered table-sm">`)
+	out.Writef(`<thead><tr><th>%s</th><th>Used</th><th>Unused</th><th>Utilization</th><th>Net savings</th></tr></thead>`, totalTitle)
+	out.Writeln(`<tbody><tr>`)
+	out.Writef(`<td align="right">%s</td>`, format(utilization.Total))
+	out.Writef(`<td align="right">%s</td>`, format(utilization.Used))
+	out.Writef(`<td align="right">%s</td>`, format(utilization.Unused))
+	out.Writef(`<td align="right" class="%s"><strong>%s%%</strong></td>`, utilizationClass,
+		humanize.CommafWithDigits(utilization.UtilizationPercentage, 2))
+	out.Writef(`<td align="right">%s</td>`, cloudcostexplorer.FormatMoney(utilization.NetSavings))
+	out.Writeln(`</tr></tbody></table>`)
+}
+
+// writeCommitmentCoverage outputs the commitment coverage grouped by the selected dimension, with links to filter
+// by each value if "drillDown" is true.
+func writeCommitmentCoverage(out *ui2.HTTPOutput, uq *cloudcostexplorer.URLQuery, groupBy aws2.CommitmentDimension,
+	drillDown bool, format func(float64) string, coverage aws2.CommitmentCoverage) {
+	out.Writeln(`<h5>Coverage</h5>`)
+	if coverage.Err != nil {
+		out.Writef(`<p>error: %s</p>`, coverage.Err.Error())
+		return
+	}
+	writeIgnoredCommitmentFilters(out, coverage.IgnoredFilters)
+	if !coverage.IsGrouped {
+		out.Writef(`<p class="text-muted">Grouping by %s is not supported by this API, showing totals.</p>`, groupBy.Title())
+	}
+
+	// when filtering by a value, group by the next dimension which is not filtered yet.
+	nextGroup := groupBy
+	for _, dim := range aws2.CommitmentDimensions {
+		if dim != groupBy && uq.Get(fmt.Sprintf("f%s", dim)) == "" {
+			nextGroup = dim
+			break
+		}
+	}
+
+	writeRow := func(title string, item *aws2.CommitmentCoverageItem, isTotal bool) {
+		coverageClass := "text-success"
+		if item.CoveragePercentage() < 80 {
+			coverageClass = "text-danger"
+		}
+		out.Writeln(`<tr>`)
+		if isTotal {
+			out.Writef(`<td><strong>%s</strong></td>`, title)
+		} else {
+			out.Writef(`<td>%s</td>`, title)
+		}
+		out.Writef(`<td align="right">%s</td>`, format(item.Covered))
+		out.Writef(`<td align="right">%s</td>`, format(item.OnDemand))
+		out.Writef(`<td align="right">%s</td>`, format(item.Total))
+		out.Writef(`<td align="right" class="%s">%s%%</td>`, coverageClass, humanize.CommafWithDigits(item.CoveragePercentage(), 2))
+		out.Writeln(`</tr>`)
+	}
+
+	out.Writeln(`<table class="table table-striped table-bordered table-sm">`)
+	groupTitle := ""
+	if coverage.IsGrouped {
+		groupTitle = groupBy.Title()
+	}
+	out.Writef(`<thead><tr><th>%s</th><th>Covered</th><th>On-demand</th><th>Total</th><th>Coverage</th></tr></thead>`, groupTitle)
+	out.Writeln(`<tbody>`)
+	writeRow("TOTAL", &coverage.Total, true)
+	for _, item := range coverage.Items {
+		title := item.Key
+		if title == "" {
+			title = "[BLANK]"
+		}
+		if drillDown && item.Key != "" {
+			title = fmt.Sprintf(`<a href="%s">%s</a>`,
+				uq.Clone().Set(fmt.Sprintf("f%s", groupBy), item.Key).Set("group", string(nextGroup)),
+				title)
+		}
+		writeRow(title, item, false)
+	}
+	out.Writeln(`</tbody></table>`)
+}
+
+func writeIgnoredCommitmentFilters(out *ui2.HTTPOutput, ignoredFilters []aws2.CommitmentDimension) {
+	for _, dim := range ignoredFilters {
+		out.Writef(`<p class="text-muted">Filtering by %s is not supported by this API and was ignored.</p>`, dim.Title())
+	}
+}
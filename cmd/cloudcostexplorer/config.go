@@ -3,66 +3,159 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 
+	"cloud.google.com/go/bigquery"
 	"github.com/BurntSushi/toml"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/dustin/go-humanize"
 	"github.com/rrgmc/cloudcostexplorer"
 	aws2 "github.com/rrgmc/cloudcostexplorer/cloud/aws"
 	gcp2 "github.com/rrgmc/cloudcostexplorer/cloud/gcp"
 )
 
-type Config map[string]ConfigItem
+// Config is the configuration file. The top-level keys are the server settings, and each table is a cloud.
+type Config struct {
+	LogLevel string                // log level of the server and all clouds: debug, info, warn or error. Default is info.
+	Clouds   map[string]ConfigItem // by config item name.
+}
 
 type ConfigItem struct {
 	Disabled bool   `toml:"disabled"`
 	Cloud    string `toml:"cloud"`
+	Timezone string `toml:"timezone"` // IANA timezone name used for billing days, like "America/Los_Angeles". Default is UTC.
 	// AWS
 	Profile string `toml:"profile"`
 	Region  string `toml:"region"`
+	// role to assume using the profile credentials, like "arn:aws:iam::123456789012:role/CostExplorer".
+	RoleArn     string `toml:"role_arn"`
+	ExternalID  string `toml:"external_id"`
+	SessionName string `toml:"session_name"`
+	Duration    string `toml:"duration"` // assumed role session duration, like "1h". Default is 15 minutes.
+	// names of the environment variables containing static credentials, used instead of the profile credentials.
+	AccessKeyIDEnv     string `toml:"access_key_id_env"`
+	SecretAccessKeyEnv string `toml:"secret_access_key_env"`
+	SessionTokenEnv    string `toml:"session_token_env"`
+	// endpoint override for all AWS services, like "http://localhost:4566" to use a local stand-in.
+	Endpoint string `toml:"endpoint"`
+	// interval to reload the organization accounts, OUs and account tags, like "1h". Default is to load only once.
+	OrgRefreshInterval string `toml:"org_refresh_interval"`
+	// maximum number of cost explorer API calls per UTC day, each one is billed. Default is unlimited.
+	DailyAPICallBudget int64 `toml:"daily_api_call_budget"`
 	// GCP
 	ProjectID     string `toml:"project_id"`
 	DefaultTable  string `toml:"default_table"`
 	ResourceTable string `toml:"resource_table"`
+	// maximum bytes a query may scan without confirmation, like "100GiB". Each query is dry-run first.
+	MaxBytesScanned string `toml:"max_bytes_scanned"`
+	// maximum bytes billed of each query job, like "1TiB". Queries above it fail without being billed.
+	MaxBytesBilled string            `toml:"max_bytes_billed"`
+	JobLocation    string            `toml:"job_location"` // like "US" or "europe-west1".
+	JobPriority    string            `toml:"job_priority"` // interactive or batch. Default is interactive.
+	JobLabels      map[string]string `toml:"job_labels"`
 }
 
-func LoadConfig() (Config, error) {
+func LoadConfig() (*Config, error) {
 	f, err := os.Open("cloudcostexplorer.conf")
 	if err != nil {
 		return nil, fmt.Errorf("error loading config file: %w", err)
 	}
 	defer f.Close()
 
-	var config Config
-	_, err = toml.NewDecoder(f).Decode(&config)
+	var values map[string]toml.Primitive
+	md, err := toml.NewDecoder(f).Decode(&values)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing config file: %w", err)
 	}
 
+	config := &Config{
+		Clouds: map[string]ConfigItem{},
+	}
+	for key, value := range values {
+		var err error
+		if md.Type(key) == "Hash" {
+			var item ConfigItem
+			err = md.PrimitiveDecode(value, &item)
+			config.Clouds[key] = item
+		} else {
+			switch key {
+			case "log_level":
+				err = md.PrimitiveDecode(value, &config.LogLevel)
+			default:
+				err = fmt.Errorf("unknown setting '%s'", key)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing config file: %w", err)
+		}
+	}
+
 	return config, nil
 }
 
-func CreateCloud(ctx context.Context, item ConfigItem) (cloudcostexplorer.Cloud, error) {
-	switch item.Cloud {
-	case "AWS":
-		var optns []func(*config.LoadOptions) error
-		if item.Profile != "" {
-			optns = append(optns, config.WithSharedConfigProfile(item.Profile))
-		}
-		if item.Region != "" {
-			optns = append(optns, config.WithRegion(item.Region))
+// Logger returns the server logger with the configured level. Each cloud uses a child logger with its config item
+// name.
+func (c *Config) Logger() (*slog.Logger, error) {
+	var level slog.Level
+	if c.LogLevel != "" {
+		if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+			return nil, fmt.Errorf("invalid log level '%s': %w", c.LogLevel, err)
 		}
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: level,
+	})), nil
+}
 
-		cfg, err := config.LoadDefaultConfig(ctx, optns...)
+// Location returns the configured timezone location, or UTC if not set.
+func (c ConfigItem) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone '%s': %w", c.Timezone, err)
+	}
+	return loc, nil
+}
+
+// CreateCloud creates the cloud of the config item, using the location returned by [ConfigItem.Location].
+func CreateCloud(ctx context.Context, item ConfigItem, location *time.Location, logger *slog.Logger) (cloudcostexplorer.Cloud, error) {
+	switch item.Cloud {
+	case "AWS":
+		cfg, err := item.AWSConfig(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("unable to load AWS SDK config: %v", err)
+			return nil, err
 		}
 
-		return aws2.New(ctx,
+		optns := []aws2.CloudOption{
 			aws2.WithCloudConfig(cfg),
-		)
+			aws2.WithLogger(logger),
+		}
+		if item.OrgRefreshInterval != "" {
+			interval, err := time.ParseDuration(item.OrgRefreshInterval)
+			if err != nil {
+				return nil, fmt.Errorf("invalid org_refresh_interval '%s': %w", item.OrgRefreshInterval, err)
+			}
+			optns = append(optns, aws2.WithOrganizationRefreshInterval(interval))
+		}
+		if item.DailyAPICallBudget > 0 {
+			optns = append(optns, aws2.WithDailyAPICallBudget(item.DailyAPICallBudget))
+		}
+
+		return aws2.New(ctx, optns...)
 	case "GCP":
-		var optns []gcp2.CloudOption
+		optns := []gcp2.CloudOption{
+			gcp2.WithLocation(location),
+			gcp2.WithLogger(logger),
+		}
 		if item.ProjectID != "" {
 			optns = append(optns, gcp2.WithProjectID(item.ProjectID))
 		}
@@ -72,9 +165,94 @@ func CreateCloud(ctx context.Context, item ConfigItem) (cloudcostexplorer.Cloud,
 		if item.ResourceTable != "" {
 			optns = append(optns, gcp2.WithResourceTableName(item.ResourceTable))
 		}
+		if item.MaxBytesScanned != "" {
+			maxBytes, err := humanize.ParseBytes(item.MaxBytesScanned)
+			if err != nil {
+				return nil, fmt.Errorf("invalid max_bytes_scanned '%s': %w", item.MaxBytesScanned, err)
+			}
+			optns = append(optns, gcp2.WithMaxBytesScanned(int64(maxBytes)))
+		}
+		if item.MaxBytesBilled != "" {
+			maxBytes, err := humanize.ParseBytes(item.MaxBytesBilled)
+			if err != nil {
+				return nil, fmt.Errorf("invalid max_bytes_billed '%s': %w", item.MaxBytesBilled, err)
+			}
+			optns = append(optns, gcp2.WithMaxBytesBilled(int64(maxBytes)))
+		}
+		if item.JobLocation != "" {
+			optns = append(optns, gcp2.WithJobLocation(item.JobLocation))
+		}
+		switch strings.ToLower(item.JobPriority) {
+		case "", "interactive":
+		case "batch":
+			optns = append(optns, gcp2.WithJobPriority(bigquery.BatchPriority))
+		default:
+			return nil, fmt.Errorf("invalid job_priority '%s'", item.JobPriority)
+		}
+		if len(item.JobLabels) > 0 {
+			optns = append(optns, gcp2.WithJobLabels(item.JobLabels))
+		}
 
 		return gcp2.New(ctx, optns...)
 	default:
 		return nil, fmt.Errorf("cloud %s not supported", item.Cloud)
 	}
 }
+
+// AWSConfig loads the AWS config from the profile or static credentials, assuming the configured role if set.
+func (c ConfigItem) AWSConfig(ctx context.Context) (aws.Config, error) {
+	var optns []func(*config.LoadOptions) error
+	if c.Profile != "" {
+		optns = append(optns, config.WithSharedConfigProfile(c.Profile))
+	}
+	if c.Region != "" {
+		optns = append(optns, config.WithRegion(c.Region))
+	}
+	if c.Endpoint != "" {
+		optns = append(optns, config.WithBaseEndpoint(c.Endpoint))
+	}
+	if c.AccessKeyIDEnv != "" || c.SecretAccessKeyEnv != "" {
+		accessKeyID, secretAccessKey := os.Getenv(c.AccessKeyIDEnv), os.Getenv(c.SecretAccessKeyEnv)
+		if accessKeyID == "" || secretAccessKey == "" {
+			return aws.Config{}, fmt.Errorf("static credentials environment variables '%s' and '%s' must be set",
+				c.AccessKeyIDEnv, c.SecretAccessKeyEnv)
+		}
+		var sessionToken string
+		if c.SessionTokenEnv != "" {
+			sessionToken = os.Getenv(c.SessionTokenEnv)
+		}
+		optns = append(optns, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, sessionToken)))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, optns...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("unable to load AWS SDK config: %v", err)
+	}
+
+	if c.RoleArn != "" {
+		var duration time.Duration
+		if c.Duration != "" {
+			duration, err = time.ParseDuration(c.Duration)
+			if err != nil {
+				return aws.Config{}, fmt.Errorf("invalid duration '%s': %w", c.Duration, err)
+			}
+		}
+
+		// the credentials cache refreshes the assumed role credentials before they expire.
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), c.RoleArn,
+			func(o *stscreds.AssumeRoleOptions) {
+				if c.ExternalID != "" {
+					o.ExternalID = aws.String(c.ExternalID)
+				}
+				if c.SessionName != "" {
+					o.RoleSessionName = c.SessionName
+				}
+				if duration > 0 {
+					o.Duration = duration
+				}
+			}))
+	}
+
+	return cfg, nil
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"html"
 	"math"
 	"net/http"
-	"net/url"
 	"slices"
 	"strings"
 	"time"
@@ -12,13 +14,14 @@ import (
 	"github.com/dustin/go-humanize"
 	"github.com/invzhi/timex"
 	"github.com/rrgmc/cloudcostexplorer"
+	aws2 "github.com/rrgmc/cloudcostexplorer/cloud/aws"
 	ui2 "github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
 )
 
-func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handler {
+func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud, location *time.Location) http.Handler {
 	return ui2.HTTPHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
 
-		rootPath := fmt.Sprintf("/costexplorer/%s", url.PathEscape(item))
+		rootPath := pagePath("costexplorer", item)
 
 		uq := cloudcostexplorer.NewURLQuery(rootPath)
 
@@ -31,6 +34,11 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 		var sortidx int
 		var sortdir string
 		var search string
+		var istree bool
+		var ispivot bool
+		var pivotidx int
+		var normalization costNormalization
+		var showcredits bool
 
 		if limit, paramExists = HTTPQueryIntValue(r, "limit", 200); paramExists {
 			uq.Set("limit", fmt.Sprintf("%d", limit))
@@ -56,6 +64,22 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 		if search, paramExists = HTTPQueryStringValue(r, "search", ""); paramExists {
 			uq.Set("search", search)
 		}
+		if istree, paramExists = HTTPQueryBoolValue(r, "tree", false); paramExists {
+			uq.Set("tree", fmt.Sprintf("%t", istree))
+		}
+		if ispivot, paramExists = HTTPQueryBoolValue(r, "pivot", false); paramExists {
+			uq.Set("pivot", fmt.Sprintf("%t", ispivot))
+		}
+		if pivotidx, paramExists = HTTPQueryIntValue(r, "pivotidx", -1); paramExists {
+			uq.Set("pivotidx", fmt.Sprintf("%d", pivotidx))
+		}
+		if normalizeValue, paramExists := HTTPQueryStringValue(r, "normalize", ""); paramExists {
+			normalization = parseCostNormalization(normalizeValue)
+			uq.Set("normalize", string(normalization))
+		}
+		if showcredits, paramExists = HTTPQueryBoolValue(r, "showcredits", false); paramExists {
+			uq.Set("showcredits", fmt.Sprintf("%t", showcredits))
+		}
 
 		// filters
 		var filters []cloudcostexplorer.QueryFilter
@@ -70,6 +94,28 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 			if !ok {
 				continue
 			}
+			if parameter.HasData {
+				// parameters with data can be repeated, each value is a separate filter, e.g. multiple label keys.
+				for _, filterValue := range queryParamValue {
+					if filterValue == "" {
+						continue
+					}
+					uq.Add(queryParamName, filterValue)
+
+					filters = append(filters, cloudcostexplorer.QueryFilter{
+						ID:    parameter.ID,
+						Value: filterValue,
+					})
+					activeFilters = append(activeFilters, activeFilter{
+						parameter:  parameter,
+						title:      cloud.ParameterTitle(parameter.ID, filterValue),
+						paramNames: []string{queryParamName},
+						paramValue: filterValue,
+					})
+				}
+				continue
+			}
+
 			filterValue := strings.Join(queryParamValue, ",")
 			uq.Set(queryParamName, filterValue)
 
@@ -121,7 +167,7 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 			})
 		}
 
-		periodList, periodDesc, err := ParsePeriod(r)
+		periodList, periodDesc, err := ParsePeriod(r, location)
 		if err != nil {
 			return err
 		}
@@ -148,16 +194,45 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 
 		var periodMatchErrors []error
 
-		queryData, err := cloudcostexplorer.QueryHandler(r.Context(), cloud,
+		// confirms running queries which are estimated to be expensive. Not kept in the page links, so each new
+		// expensive query must be confirmed again.
+		confirmed, _ := HTTPQueryBoolValue(r, "confirm", false)
+
+		// get the AWS cost anomalies while the query runs, unless the API call budget was exhausted.
+		var anomaliesFuture <-chan anomaliesResult
+		if awsCloud, ok := cloud.(*aws2.Cloud); ok && !awsCloud.CallStats().Exhausted() && slices.ContainsFunc(groups, func(g cloudcostexplorer.QueryGroup) bool {
+			return slices.Contains(anomalyMarkerParameters, g.ID)
+		}) {
+			if ok, start, end := periodList[len(periodList)-1].Range(); ok {
+				anomaliesCtx, anomaliesCancel := context.WithCancel(r.Context())
+				defer anomaliesCancel()
+				anomaliesFuture = detectedAnomaliesFuture(anomaliesCtx, awsCloud, item, start, end)
+			}
+		}
+
+		queryOptions := []cloudcostexplorer.QueryHandlerOption{
 			cloudcostexplorer.WithQueryHandlerFilters(filters...),
 			cloudcostexplorer.WithQueryHandlerGroups(groups...),
 			cloudcostexplorer.WithQueryHandlerPeriodLists(periodList...),
+			cloudcostexplorer.WithQueryHandlerConfirmed(confirmed),
 			cloudcostexplorer.WithQueryHandlerOnPeriodMatchError(func(item cloudcostexplorer.CloudQueryItem, matchCount int) error {
 				periodMatchErrors = append(periodMatchErrors, fmt.Errorf("period '%s' should match 1 but matched %d", item.Date.String(), matchCount))
 				return nil
 			}),
-		)
-		if err != nil {
+		}
+		if istree {
+			queryOptions = append(queryOptions, cloudcostexplorer.WithQueryHandlerTree())
+		}
+		if ispivot {
+			queryOptions = append(queryOptions, cloudcostexplorer.WithQueryHandlerPivot(pivotidx))
+		}
+
+		queryData, err := cloudcostexplorer.QueryHandler(r.Context(), cloud, queryOptions...)
+		var confirmationErr *cloudcostexplorer.ConfirmationRequiredError
+		if errors.As(err, &confirmationErr) {
+			writeConfirmationRequired(w, r, item, cloud, uq, confirmationErr)
+			return nil
+		} else if err != nil {
 			return err
 		}
 
@@ -169,6 +244,16 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 			defer queryData.ExtraOutput.Close()
 		}
 
+		// mark items which are root causes of AWS cost anomalies in the main period.
+		var markers *anomalyMarkers
+		if anomaliesFuture != nil {
+			result := <-anomaliesFuture
+			if result.err != nil {
+				periodMatchErrors = append(periodMatchErrors, result.err)
+			}
+			markers = newAnomalyMarkers(result.anomalies, result.query, filters)
+		}
+
 		out := ui2.NewHTTPOutput(w)
 
 		out.DocBegin(fmt.Sprintf("%s - CloudCostExplorer", item))
@@ -228,7 +313,8 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 
 		// PERIOD BEGIN
 
-		yesterday := timex.Today(time.UTC).AddDays(-1)
+		today := timex.Today(location)
+		yesterday := today.AddDays(-1)
 		for p := range 3 {
 			if p > 1 && !IsPeriod2(r) {
 				break
@@ -260,6 +346,14 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 				out.NavDropdownItem("REPEAT 3", uq.Clone().Set(periodParam, "R3").String())
 				out.NavDropdownItem("REPEAT 7", uq.Clone().Set(periodParam, "R7").String())
 				out.NavDropdownItem("REPEAT 30", uq.Clone().Set(periodParam, "R30").String())
+				out.NavDropdownItem("CALENDAR WEEKS 4", uq.Clone().Set(periodParam, "RW4").String())
+				out.NavDropdownItem("CALENDAR WEEKS 8", uq.Clone().Set(periodParam, "RW8").String())
+				out.NavDropdownItem("CALENDAR MONTHS 3", uq.Clone().Set(periodParam, "RM3").String())
+				out.NavDropdownItem("CALENDAR MONTHS 6", uq.Clone().Set(periodParam, "RM6").String())
+				out.NavDropdownItem("CALENDAR MONTHS 12", uq.Clone().Set(periodParam, "RM12").String())
+				out.NavDropdownItem("CALENDAR QUARTERS 4", uq.Clone().Set(periodParam, "RQ4").String())
+				out.NavDropdownItem("YEAR OVER YEAR 2", uq.Clone().Set(periodParam, "RY2").String())
+				out.NavDropdownItem("YEAR OVER YEAR 3", uq.Clone().Set(periodParam, "RY3").String())
 				out.NavDropdownDivider()
 			}
 			out.NavDropdownItem("Yesterday", uq.Clone().Set(periodParam, fmt.Sprintf("T%s", yesterday.Format("YYYY-MM-DD"))).String())
@@ -271,10 +365,21 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 			out.NavDropdownItem("3 months", uq.Clone().Set(periodParam, "m3").String())
 			out.NavDropdownItem("6 months", uq.Clone().Set(periodParam, "m6").String())
 			out.NavDropdownItem("12 months", uq.Clone().Set(periodParam, "m12").String())
-			curYear, curMonth, curDay := time.Now().Date()
+			out.NavDropdownItem("Month to date", uq.Clone().Set(periodParam, "MTD").String())
+			out.NavDropdownItem("Quarter to date", uq.Clone().Set(periodParam, "QTD").String())
+			out.NavDropdownItem("Year to date", uq.Clone().Set(periodParam, "YTD").String())
+			out.NavDropdownItem("Last complete week", uq.Clone().Set(periodParam, "LW").String())
+			out.NavDropdownItem("Last complete month", uq.Clone().Set(periodParam, "LM").String())
+			out.NavDropdownItem("Last complete quarter", uq.Clone().Set(periodParam, "LQ").String())
+			out.NavDropdownItem("Last complete year", uq.Clone().Set(periodParam, "LY").String())
+			curYear, curMonth, curDay := time.Now().In(location).Date()
 			for dct := range 8 {
 				out.NavDropdownItem(fmt.Sprintf("%s/%04d", cloudcostexplorer.ShortMonthName(curMonth), curYear),
 					uq.Clone().Set(periodParam, fmt.Sprintf("M%04d%02d", curYear, curMonth)).String())
+				if dct < 4 {
+					out.NavDropdownItem(fmt.Sprintf("%s/%04d invoice", cloudcostexplorer.ShortMonthName(curMonth), curYear),
+						uq.Clone().Set(periodParam, fmt.Sprintf("I%04d%02d", curYear, curMonth)).String())
+				}
 				if dct < 3 && curDay > 2 {
 					out.NavDropdownItem(fmt.Sprintf("%s/%04d to day", cloudcostexplorer.ShortMonthName(curMonth), curYear),
 						uq.Clone().Set(periodParam, fmt.Sprintf("T%04d-%02d-01|%04d-%02d-%02d", curYear, curMonth, curYear, curMonth, curDay-2)).String())
@@ -285,6 +390,16 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 					curYear -= 1
 				}
 			}
+			curQuarter := cloudcostexplorer.StartOfQuarter(today)
+			for range 4 {
+				out.NavDropdownItem(fmt.Sprintf("Q%d/%04d", curQuarter.Quarter(), curQuarter.Year()),
+					uq.Clone().Set(periodParam, fmt.Sprintf("Q%04d-%d", curQuarter.Year(), curQuarter.Quarter())).String())
+				curQuarter = curQuarter.Add(0, -3, 0)
+			}
+			for yct := range 2 {
+				out.NavDropdownItem(fmt.Sprintf("%04d", today.Year()-yct),
+					uq.Clone().Set(periodParam, fmt.Sprintf("Y%04d", today.Year()-yct)).String())
+			}
 			out.NavDropdownEnd()
 		}
 
@@ -315,20 +430,59 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 		}
 		out.NavDropdownItem("Toggle cost difference value", hcdv.String())
 		out.NavDropdownItem("Toggle cost difference %", hcdp.String())
+		if queryData.HasCredits() {
+			if showcredits {
+				out.NavDropdownItem("Hide gross cost and credits", uq.Clone().Remove("showcredits").String())
+			} else {
+				out.NavDropdownItem("Show gross cost and credits", uq.Clone().Set("showcredits", "1").String())
+			}
+		}
+		out.NavDropdownDivider()
+		out.NavDropdownHeader("Compare periods using")
+		out.NavDropdownItem("Period totals", uq.Clone().Remove("normalize").String())
+		out.NavDropdownItem("Average daily cost", uq.Clone().Set("normalize", string(costNormalizationDaily)).String())
+		out.NavDropdownItem("Scaled to same number of days", uq.Clone().Set("normalize", string(costNormalizationScale)).String())
+		out.NavDropdownDivider()
+		if istree || ispivot {
+			out.NavDropdownItem("Flat view", uq.Clone().Remove("tree", "pivot", "pivotidx").String())
+		}
+		if len(groups) > 1 && !istree {
+			out.NavDropdownItem("Tree view (group subtotals)", uq.Clone().Remove("pivot", "pivotidx").Set("tree", "1").String())
+		}
+		if !ispivot {
+			if len(groups) > 1 {
+				out.NavDropdownItem("Pivot view (last group as columns)", uq.Clone().Remove("tree").Set("pivot", "1").String())
+			} else {
+				out.NavDropdownItem("Pivot view (periods as columns)", uq.Clone().Remove("tree").Set("pivot", "1").String())
+			}
+		}
 		out.NavDropdownEnd()
 
 		// PERIOD END
 
+		writePagesMenu(out, item, cloud)
+
 		out.NavMenuEnd()
 
 		out.NavTextCustom(`<span class="badge bg-secondary">Period</span>`, periodDesc)
+		if _, ok := cloud.(*aws2.Cloud); ok {
+			if _, _, ok := monthComparison(periodList); ok {
+				out.NavTextCustom(fmt.Sprintf(`<a class="badge bg-primary text-decoration-none" title="Explain the cost difference using the AWS cost comparison drivers" href="%s">Explain</a>`,
+					uq.Clone().SetPath(pagePath("explain", item))), "")
+			}
+		}
+		if normalization != costNormalizationNone {
+			out.NavTextCustom(fmt.Sprintf(`<span class="badge bg-secondary">Compare <a href="%s"><i class="bi bi-trash text-white"></i></a></span>`,
+				uq.Clone().Remove("normalize")),
+				normalization.Title())
+		}
 
 		// FILTERS BEGIN
 
 		for _, actiteFilter := range activeFilters {
 			out.NavTextCustom(fmt.Sprintf(`<span class="badge bg-secondary">%s <a href="%s"><i class="bi bi-trash text-white"></i></a></span>`,
 				actiteFilter.parameter.Name,
-				uq.Clone().Remove(actiteFilter.paramNames...)),
+				actiteFilter.removeQuery(uq)),
 				cloudcostexplorer.EllipticalTruncate(actiteFilter.title, 32))
 		}
 
@@ -341,6 +495,13 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 		out.BodyBegin()
 
 		// DATA
+		if ispivot {
+			if err := writePivotTable(r, w, out, cloud, uq, queryData, limit, mincost, search); err != nil {
+				return err
+			}
+			return writeCostExplorerEnd(r, w, out, cloud, uq, queryData, periodMatchErrors)
+		}
+
 		out.Writeln(`<table class="table table-striped table-bordered table-sm">`)
 
 		// HEADER BEGIN
@@ -349,19 +510,29 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 		for gidx, currentgroup := range queryData.Groups {
 			out.Writef(`<th>%s (%d)</th>`, currentgroup.Title(true), gidx+1)
 		}
+		diffTitle := "Diff"
+		switch normalization {
+		case costNormalizationDaily:
+			diffTitle = "Diff/day"
+		case costNormalizationScale:
+			diffTitle = "Diff (scaled)"
+		}
 		for periodIdx, period := range queryData.Periods {
 			if periodIdx > 0 && showdiff {
-				out.Writef(`<th>Diff&nbsp;%s</th>`,
+				out.Writef(`<th title="%s">%s&nbsp;%s</th>`, normalization.Title(), diffTitle,
 					ui2.SortIcon(sort == "diff" && sortidx == periodIdx, sortdir,
 						uq.Clone().Set("sort", "diff").
 							Set("sortidx", fmt.Sprintf("%d", periodIdx))))
 			}
 			if periodIdx > 0 && showdiffpct {
-				out.Writef(`<th>Diff%%&nbsp;%s</th>`,
+				out.Writef(`<th title="%s">%s%%&nbsp;%s</th>`, normalization.Title(), diffTitle,
 					ui2.SortIcon(sort == "diffpct" && sortidx == periodIdx, sortdir,
 						uq.Clone().Set("sort", "diffpct").
 							Set("sortidx", fmt.Sprintf("%d", periodIdx))))
 			}
+			if showcredits {
+				out.Writeln(`<th>Gross</th><th>Credits</th>`)
+			}
 			periodIcon := ""
 			if periodIdx == len(queryData.Periods)-1 {
 				periodIcon = fmt.Sprintf(`&nbsp;%s`,
@@ -370,7 +541,7 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 				periodIcon = fmt.Sprintf(`&nbsp;<a title="Filter only this period" class="link-secondary" href="%s"><i class="bi bi-filter-circle"></i></a>`,
 					uq.Clone().Remove(periodParams...).Set("period", period.StringFilter()))
 			}
-			out.Writef(`<th>%s%s</th>`, period.StringWithDuration(!queryData.PeriodsSameDuration), periodIcon)
+			out.Writef(`<th>%s%s</th>`, period.Format(queryData.PeriodsMultipleYears(), !queryData.PeriodsSameDuration), periodIcon)
 		}
 		out.Writeln(`</tr></thead>`)
 		// HEADER END
@@ -384,7 +555,7 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 		for periodIdx, period := range queryData.Periods {
 			costClass := ""
 			if periodIdx > 0 {
-				costDiff, pctCostDiff := periodCostDiffGet(periodIdx, queryData.Periods)
+				costDiff, pctCostDiff := periodCostDiffGet(periodIdx, queryData.Periods, normalization)
 
 				costClass = "text-danger"
 				if costDiff <= 0 {
@@ -401,6 +572,10 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 				}
 			}
 
+			if showcredits {
+				out.Writef(`<td align="right"><strong>%s</strong></td><td align="right"><strong>%s</strong></td>`,
+					cloudcostexplorer.FormatMoney(period.TotalGrossValue()), cloudcostexplorer.FormatMoney(period.TotalCredits))
+			}
 			out.Writef(`<td class="%s" align="right"><strong>%s</strong></td>`,
 				costClass, cloudcostexplorer.FormatMoney(period.TotalValue))
 		}
@@ -419,22 +594,29 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 		if showdiffpct {
 			totalCols += len(queryData.Periods) - 1
 		}
+		if showcredits {
+			totalCols += 2 * len(queryData.Periods)
+		}
 
-		slices.SortFunc(queryData.Items, func(a, b *cloudcostexplorer.Item) int {
+		itemCompare := func(a, b *cloudcostexplorer.Item) int {
 			if sort == "diff" || sort == "diffpct" {
 				if sortidx > 0 && sortidx < len(queryData.Periods) {
 					if sort == "diff" {
-						return compare(math.Abs(itemCostDiff(sortidx, a)), math.Abs(itemCostDiff(sortidx, b)), sortdir != "A")
+						return compare(math.Abs(itemCostDiff(sortidx, a, queryData.Periods, normalization)), math.Abs(itemCostDiff(sortidx, b, queryData.Periods, normalization)), sortdir != "A")
 					}
-					return compare(math.Abs(itemCostDiffPct(sortidx, a)), math.Abs(itemCostDiffPct(sortidx, b)), sortdir != "A")
+					return compare(math.Abs(itemCostDiffPct(sortidx, a, queryData.Periods, normalization)), math.Abs(itemCostDiffPct(sortidx, b, queryData.Periods, normalization)), sortdir != "A")
 				}
 			}
 			return compare(a.Values[len(b.Values)-1], b.Values[len(b.Values)-1], true)
-		})
-		for _, item := range queryData.Items {
+		}
+
+		// itemSkipped checks the search and minimum cost limits, incrementing the skip counters if requested.
+		itemSkipped := func(item *cloudcostexplorer.Item, count bool) bool {
 			if search != "" && !item.Search(search) {
-				skipSearch++
-				continue
+				if count {
+					skipSearch++
+				}
+				return true
 			}
 
 			var maxCostValue float64
@@ -444,42 +626,40 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 				}
 			}
 			if mincost > 0 && maxCostValue < float64(mincost) {
-				skipMinCost++
-				continue
+				if count {
+					skipMinCost++
+				}
+				return true
 			}
+			return false
+		}
 
-			out.Writeln(`<tr>`)
+		// writeItemRow outputs an item row, starting to output the item keys from the "startKey" group.
+		writeItemRow := func(item *cloudcostexplorer.Item, rowTitle string, rowClass string, startKey int, isSubtotal bool) error {
+			out.Writef(`<tr class="%s">`+"\n", rowClass)
 
-			out.Writef(`<td align="center">%d</td>`, ct)
+			out.Writef(`<td align="center">%s</td>`, rowTitle)
 
-			for groupIdx, group := range item.Keys {
-				switch gv := group.Value.(type) {
-				case cloudcostexplorer.ValueOutput:
-					ov, err := gv.Output(r.Context(), newValueContext(fmt.Sprintf("group%d", groupIdx+1), w), uq.Clone())
-					if err != nil {
-						return fmt.Errorf("error handling custom value: %w", err)
-					}
-					out.Writef(`<td>%s</td>`, ov)
-				default:
-					if queryData.Groups[groupIdx].IsGroupFilter {
-						gq := uq.Clone().Set(fmt.Sprintf("f%s", queryData.Groups[groupIdx].ID), group.ID)
-						// if only one group and filtering by one of its values, change the group to the one with the next priority.
-						if len(queryData.Groups) == 1 && queryData.Groups[groupIdx].DefaultPriority > 0 {
-							gf, ok := cloud.Parameters().FindByGroupDefaultPriority(queryData.Groups[groupIdx].DefaultPriority + 1)
-							if ok {
-								gq.Set("group1", gf.ID)
-							}
-						}
-						out.Writef(`<td><a href="%s">%s</a></td>`, gq, group.Value)
-					} else {
-						out.Writef(`<td>%s</td>`, group.Value)
-					}
+			for groupIdx := range queryData.Groups {
+				if groupIdx < startKey || groupIdx >= len(item.Keys) {
+					out.Write(`<td></td>`)
+					continue
+				}
+				group := item.Keys[groupIdx]
+
+				ov, err := itemKeyOutput(r, w, cloud, uq, queryData.Groups, groupIdx, group, isSubtotal)
+				if err != nil {
+					return err
 				}
+				if groupIdx == len(item.Keys)-1 {
+					ov += markers.output(queryData.Groups, item.Keys)
+				}
+				out.Writef(`<td>%s</td>`, ov)
 			}
 			for periodIdx, periodValue := range item.Values {
 				costClass := ""
 				if periodIdx > 0 {
-					costDiff, pctCostDiff := itemCostDiffGet(periodIdx, item)
+					costDiff, pctCostDiff := itemCostDiffGet(periodIdx, item, queryData.Periods, normalization)
 
 					costClass = "text-danger"
 					if costDiff <= 0 {
@@ -495,15 +675,100 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 						costClass = ""
 					}
 				}
-				out.Writef(`<td class="%s" align="right">%s</td>`, costClass, cloudcostexplorer.FormatMoney(periodValue))
+				if showcredits {
+					out.Writef(`<td align="right">%s</td><td align="right">%s</td>`,
+						cloudcostexplorer.FormatMoney(item.GrossValue(periodIdx)), cloudcostexplorer.FormatMoney(item.Credits[periodIdx]))
+				}
+				if isSubtotal {
+					out.Writef(`<td class="%s" align="right"><strong>%s</strong></td>`, costClass, cloudcostexplorer.FormatMoney(periodValue))
+				} else {
+					out.Writef(`<td class="%s" align="right">%s</td>`, costClass, cloudcostexplorer.FormatMoney(periodValue))
+				}
 			}
 
 			out.Writeln(`</tr>`)
+			return nil
+		}
 
-			ct++
-			if limit > 0 && ct > limit {
-				isLimit = true
-				break
+		if istree {
+			cloudcostexplorer.SortItemTree(queryData.Tree, itemCompare)
+
+			// a node is visible if any of its leaves is not skipped.
+			visibleNodes := map[*cloudcostexplorer.ItemTreeNode]bool{}
+			var checkVisible func(nodes []*cloudcostexplorer.ItemTreeNode) bool
+			checkVisible = func(nodes []*cloudcostexplorer.ItemTreeNode) bool {
+				anyVisible := false
+				for _, node := range nodes {
+					if node.IsLeaf() {
+						visibleNodes[node] = !itemSkipped(node.Item, true)
+					} else {
+						visibleNodes[node] = checkVisible(node.Children)
+					}
+					anyVisible = anyVisible || visibleNodes[node]
+				}
+				return anyVisible
+			}
+			checkVisible(queryData.Tree)
+
+			var writeTreeNodes func(nodes []*cloudcostexplorer.ItemTreeNode, parentClass string) error
+			writeTreeNodes = func(nodes []*cloudcostexplorer.ItemTreeNode, parentClass string) error {
+				for _, node := range nodes {
+					if isLimit {
+						return nil
+					}
+					if !visibleNodes[node] {
+						continue
+					}
+
+					rowClass := ""
+					if parentClass != "" {
+						rowClass = "collapse " + parentClass
+					}
+					rowTitle := fmt.Sprintf("%d", ct)
+					childClass := ""
+					if !node.IsLeaf() {
+						childClass = fmt.Sprintf("tree-%s", cloudcostexplorer.RandString(10))
+						rowTitle = fmt.Sprintf(`<a class="link-secondary" data-bs-toggle="collapse" data-bs-target=".%s" href="#" role="button" aria-expanded="false"><i class="bi bi-plus-square"></i></a>`,
+							childClass)
+					}
+
+					if err := writeItemRow(node.Item, rowTitle, rowClass, node.Level(), !node.IsLeaf()); err != nil {
+						return err
+					}
+
+					ct++
+					if limit > 0 && ct > limit {
+						isLimit = true
+						return nil
+					}
+
+					if !node.IsLeaf() {
+						if err := writeTreeNodes(node.Children, childClass); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			}
+			if err := writeTreeNodes(queryData.Tree, ""); err != nil {
+				return err
+			}
+		} else {
+			slices.SortFunc(queryData.Items, itemCompare)
+			for _, item := range queryData.Items {
+				if itemSkipped(item, true) {
+					continue
+				}
+
+				if err := writeItemRow(item, fmt.Sprintf("%d", ct), "", 0, false); err != nil {
+					return err
+				}
+
+				ct++
+				if limit > 0 && ct > limit {
+					isLimit = true
+					break
+				}
 			}
 		}
 
@@ -536,36 +801,114 @@ func handlerCostExplorer(item string, cloud cloudcostexplorer.Cloud) http.Handle
 
 		out.Writeln(`</tbody></table>`)
 
-		// extra data
+		return writeCostExplorerEnd(r, w, out, cloud, uq, queryData, periodMatchErrors)
+	})
+}
 
-		if queryData.ExtraOutput != nil {
-			for eo, err := range queryData.ExtraOutput.ExtraOutputs() {
-				if err != nil {
-					return fmt.Errorf("error handling custom value: %w", err)
-				}
+// writeCostExplorerEnd outputs the extra data, errors and query information after the cost table, and ends the
+// page.
+func writeCostExplorerEnd(r *http.Request, w http.ResponseWriter, out *ui2.HTTPOutput, cloud cloudcostexplorer.Cloud,
+	uq *cloudcostexplorer.URLQuery, queryData *cloudcostexplorer.QueryResult, periodMatchErrors []error) error {
+	// extra data
 
-				value, err := eo.Output(r.Context(), newValueContext("", w), uq.Clone())
-				if err != nil {
-					return fmt.Errorf("error handling custom value: %w", err)
-				}
+	if queryData.ExtraOutput != nil {
+		for eo, err := range queryData.ExtraOutput.ExtraOutputs() {
+			if err != nil {
+				return fmt.Errorf("error handling custom value: %w", err)
+			}
 
-				out.Writeln(value)
+			value, err := eo.Output(r.Context(), newValueContext("", w), uq.Clone())
+			if err != nil {
+				return fmt.Errorf("error handling custom value: %w", err)
 			}
+
+			out.Writeln(value)
 		}
+	}
 
-		if len(periodMatchErrors) > 0 {
-			out.Writeln(`<h3>Errors</h3>`)
+	if len(periodMatchErrors) > 0 {
+		out.Writeln(`<h3>Errors</h3>`)
 
-			out.Writeln(`<ul class="list-group">`)
-			for _, perr := range periodMatchErrors {
-				out.Writef(`<li class="list-group-item">%s</li>`+"\n", perr.Error())
-			}
-			out.Writeln(`</ul>`)
+		out.Writeln(`<ul class="list-group">`)
+		for _, perr := range periodMatchErrors {
+			out.Writef(`<li class="list-group-item">%s</li>`+"\n", perr.Error())
 		}
+		out.Writeln(`</ul>`)
+	}
 
-		out.BodyEnd()
+	writeQueryInfo(out, queryData.QueryInfo)
 
-		out.DocEnd()
-		return nil
-	})
+	out.BodyEnd()
+
+	writePageFooter(out, r, cloud)
+
+	out.DocEnd()
+	return nil
+}
+
+// writeConfirmationRequired outputs a page asking to confirm running the query, with a link to run it again
+// confirmed.
+func writeConfirmationRequired(w http.ResponseWriter, r *http.Request, item string, cloud cloudcostexplorer.Cloud,
+	uq *cloudcostexplorer.URLQuery, err *cloudcostexplorer.ConfirmationRequiredError) {
+	out := ui2.NewHTTPOutput(w)
+
+	out.DocBegin(fmt.Sprintf("%s - CloudCostExplorer", item))
+
+	out.NavBegin(uq.Path())
+	out.NavMenuBegin()
+
+	writePagesMenu(out, item, cloud)
+
+	out.NavMenuEnd()
+	out.NavEnd()
+
+	out.BodyBegin()
+
+	out.Writeln(`<div class="alert alert-warning" role="alert">`)
+	out.Writef(`<h4 class="alert-heading">Confirm query</h4><p>%s.</p>`, html.EscapeString(err.Message))
+	out.Writef(`<a class="btn btn-warning" href="%s">Run anyway</a>
+<a class="btn btn-outline-secondary" href="javascript:history.back()">Back</a>`, uq.Clone().Set("confirm", "1"))
+	out.Writeln(`</div>`)
+
+	out.BodyEnd()
+
+	writePageFooter(out, r, cloud)
+
+	out.DocEnd()
+}
+
+// itemKeyOutput returns the output of an item key of the group with index groupIdx, with a link to filter by its
+// value if the group supports it.
+func itemKeyOutput(r *http.Request, w http.ResponseWriter, cloud cloudcostexplorer.Cloud, uq *cloudcostexplorer.URLQuery,
+	groups []cloudcostexplorer.QueryResultGroup, groupIdx int, key cloudcostexplorer.ItemKey, isBold bool) (string, error) {
+	switch gv := key.Value.(type) {
+	case cloudcostexplorer.ValueOutput:
+		ov, err := gv.Output(r.Context(), newValueContext(fmt.Sprintf("group%d", groupIdx+1), w), uq.Clone())
+		if err != nil {
+			return "", fmt.Errorf("error handling custom value: %w", err)
+		}
+		return ov, nil
+	default:
+		groupValue := fmt.Sprint(key.Value)
+		if isBold {
+			groupValue = fmt.Sprintf("<strong>%s</strong>", groupValue)
+		}
+		if !groups[groupIdx].IsGroupFilter {
+			return groupValue, nil
+		}
+		var gq *cloudcostexplorer.URLQuery
+		if groups[groupIdx].HasData {
+			gq = uq.Clone().Add(fmt.Sprintf("f%s", groups[groupIdx].ID), key.ID)
+		} else {
+			gq = uq.Clone().Set(fmt.Sprintf("f%s", groups[groupIdx].ID), key.ID)
+		}
+		// if only one group and filtering by one of its values, change the group to the one with the next priority.
+		if len(groups) == 1 && groups[groupIdx].DefaultPriority > 0 {
+			gf, ok := cloud.Parameters().FindByGroupDefaultPriority(groups[groupIdx].DefaultPriority + 1)
+			if ok {
+				gq.Set("group1", gf.ID)
+			}
+		}
+		return fmt.Sprintf(`<a href="%s">%s</a>`, gq, groupValue), nil
+	}
 }
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/invzhi/timex"
+	"github.com/rrgmc/cloudcostexplorer"
+	aws2 "github.com/rrgmc/cloudcostexplorer/cloud/aws"
+	ui2 "github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
+)
+
+// handlerExplain explains the difference between two calendar months using the AWS cost comparison drivers.
+func handlerExplain(item string, cloud *aws2.Cloud, location *time.Location) http.Handler {
+	return ui2.HTTPHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		rootPath := pagePath("explain", item)
+
+		// query of the cost explorer page with the same periods and filters, removing the parameters of this page.
+		ceq := cloudcostexplorer.NewURLQueryFromValues(pagePath("costexplorer", item), r.URL.Query()).
+			Remove("limit")
+
+		limit, _ := HTTPQueryIntValue(r, "limit", 100)
+
+		// filters
+		var filters []cloudcostexplorer.QueryFilter
+		for _, parameter := range cloud.Parameters() {
+			if !parameter.IsFilter {
+				continue
+			}
+			queryParamName := fmt.Sprintf("f%s", parameter.ID)
+			queryParamValue, ok := r.URL.Query()[queryParamName]
+			if !ok {
+				continue
+			}
+			if !parameter.HasData {
+				queryParamValue = []string{strings.Join(queryParamValue, ",")}
+			}
+			for _, filterValue := range queryParamValue {
+				if filterValue == "" {
+					continue
+				}
+				filters = append(filters, cloudcostexplorer.QueryFilter{
+					ID:    parameter.ID,
+					Value: filterValue,
+				})
+			}
+		}
+
+		periodList, _, err := ParsePeriod(r, location)
+		if err != nil {
+			return err
+		}
+		baseline, comparison, ok := monthComparison(periodList)
+		if !ok {
+			return fmt.Errorf("explaining the cost difference requires comparing two complete calendar months")
+		}
+
+		report := cloud.CostComparison(r.Context(), aws2.CostComparisonQuery{
+			BaselineMonth:   baseline,
+			ComparisonMonth: comparison,
+			Filters:         filters,
+		})
+
+		out := ui2.NewHTTPOutput(w)
+
+		out.DocBegin(fmt.Sprintf("%s - Explain - CloudCostExplorer", item))
+
+		out.NavBegin(rootPath)
+		out.NavMenuBegin()
+
+		writePagesMenu(out, item, cloud)
+
+		out.NavMenuEnd()
+
+		out.NavTextCustom(`<span class="badge bg-secondary">Baseline</span>`, baseline.Format("MMM/YYYY"))
+		out.NavTextCustom(`<span class="badge bg-secondary">Comparison</span>`, comparison.Format("MMM/YYYY"))
+		for _, filter := range filters {
+			filterTitle := filter.ID
+			if parameter, ok := cloud.Parameters().FindById(filter.ID); ok {
+				filterTitle = parameter.Name
+			}
+			out.NavTextCustom(fmt.Sprintf(`<span class="badge bg-secondary">%s</span>`, filterTitle),
+				cloudcostexplorer.EllipticalTruncate(cloud.ParameterTitle(filter.ID, filter.Value), 32))
+		}
+		out.NavTextCustom(fmt.Sprintf(`<a class="badge bg-primary text-decoration-none" href="%s">Cost explorer</a>`, ceq), "")
+
+		out.NavEnd()
+
+		out.BodyBegin()
+
+		out.Writeln(`<h3>Cost drivers</h3>`)
+		if report.DriversErr != nil {
+			out.Writef(`<p>error: %s</p>`, report.DriversErr.Error())
+		} else {
+			writeCostDrivers(out, cloud, ceq, report.Drivers, limit)
+		}
+
+		out.Writeln(`<h3>By service</h3>`)
+		if report.ComparisonsErr != nil {
+			out.Writef(`<p>error: %s</p>`, report.ComparisonsErr.Error())
+		} else {
+			writeCostComparisons(out, cloud, ceq, report.Total, report.Comparisons)
+		}
+
+		out.BodyEnd()
+
+		writePageFooter(out, r, cloud)
+
+		out.DocEnd()
+
+		return nil
+	})
+}
+
+// monthComparison returns the baseline and comparison months if the periods are exactly two complete calendar
+// months.
+func monthComparison(periodList []cloudcostexplorer.QueryPeriodList) (baseline, comparison timex.Date, ok bool) {
+	var months []timex.Date
+	for _, list := range periodList {
+		for _, period := range list.Periods {
+			if period.Start.Day() != 1 || !period.End.Equal(period.Start.Add(0, 1, -1)) {
+				return timex.Date{}, timex.Date{}, false
+			}
+			months = append(months, period.Start)
+		}
+	}
+	if len(months) != 2 || months[0].Equal(months[1]) {
+		return timex.Date{}, timex.Date{}, false
+	}
+	return months[0], months[1], true
+}
+
+// writeCostDrivers outputs the cost drivers ranked by the absolute difference, each one linking to the cost
+// explorer filtered by its selector.
+func writeCostDrivers(out *ui2.HTTPOutput, cloud *aws2.Cloud, ceq *cloudcostexplorer.URLQuery,
+	drivers []*aws2.CostDriver, limit int) {
+	if len(drivers) == 0 {
+		out.Writeln(`<p>No cost drivers.</p>`)
+		return
+	}
+
+	out.Writeln(`<div class="table-responsive"><table class="table table-striped table-bordered table-sm">`)
+	out.Writeln(`<thead><tr><th>#</th><th>Selector</th><th>Driver</th><th>Name</th><th>Baseline</th><th>Comparison</th>
+<th>Difference</th></tr></thead>`)
+	out.Writeln(`<tbody>`)
+	for idx, driver := range drivers {
+		if limit > 0 && idx >= limit {
+			out.Writef(`<tr><td colspan="7" align="center">Stopped after reaching limit of %s (total was %s)</td></tr>`,
+				humanize.Comma(int64(limit)), humanize.Comma(int64(len(drivers))))
+			break
+		}
+		out.Writeln(`<tr>`)
+		out.Writef(`<td align="center">%d</td>`, idx+1)
+		out.Writef(`<td><a href="%s">%s</a></td>`, costSelectorQuery(ceq, driver.Selector), costSelectorTitle(cloud, driver.Selector))
+		out.Writef(`<td>%s</td>`, enumTitle(driver.Type))
+		out.Writef(`<td>%s</td>`, driver.Name)
+		writeCostComparisonValue(out, driver.CostComparisonValue)
+		out.Writeln(`</tr>`)
+	}
+	out.Writeln(`</tbody></table></div>`)
+}
+
+// writeCostComparisons outputs the total and the comparison of each service.
+func writeCostComparisons(out *ui2.HTTPOutput, cloud *aws2.Cloud, ceq *cloudcostexplorer.URLQuery,
+	total aws2.CostComparisonValue, comparisons []*aws2.CostComparison) {
+	out.Writeln(`<div class="table-responsive"><table class="table table-striped table-bordered table-sm">`)
+	out.Writeln(`<thead><tr><th>Service</th><th>Baseline</th><th>Comparison</th><th>Difference</th></tr></thead>`)
+	out.Writeln(`<tbody>`)
+	out.Writeln(`<tr><td><strong>TOTAL</strong></td>`)
+	writeCostComparisonValue(out, total)
+	out.Writeln(`</tr>`)
+	for _, comparison := range comparisons {
+		out.Writeln(`<tr>`)
+		out.Writef(`<td><a href="%s">%s</a></td>`, costSelectorQuery(ceq, comparison.Selector),
+			costSelectorTitle(cloud, comparison.Selector))
+		writeCostComparisonValue(out, comparison.CostComparisonValue)
+		out.Writeln(`</tr>`)
+	}
+	out.Writeln(`</tbody></table></div>`)
+}
+
+func writeCostComparisonValue(out *ui2.HTTPOutput, value aws2.CostComparisonValue) {
+	diffClass := "text-danger"
+	if value.Difference <= 0 {
+		diffClass = "text-success"
+	}
+	out.Writef(`<td align="right">%s</td>`, cloudcostexplorer.FormatMoney(value.Baseline))
+	out.Writef(`<td align="right">%s</td>`, cloudcostexplorer.FormatMoney(value.Comparison))
+	out.Writef(`<td align="right" class="%s"><strong>%s</strong></td>`, diffClass, cloudcostexplorer.FormatMoney(value.Difference))
+}
+
+// costSelectorTitle returns a description of the selector values, using the parameter names.
+func costSelectorTitle(cloud *aws2.Cloud, selector map[string]string) string {
+	if len(selector) == 0 {
+		return "[ALL]"
+	}
+	var ret []string
+	for _, id := range slices.Sorted(maps.Keys(selector)) {
+		title := id
+		if parameter, ok := cloud.Parameters().FindById(id); ok {
+			title = parameter.Name
+		}
+		ret = append(ret, fmt.Sprintf("%s: %s", title, cloud.ParameterTitle(id, selector[id])))
+	}
+	return strings.Join(ret, "<br>")
+}
+
+// costSelectorQuery returns the cost explorer query comparing the same periods filtered by the selector values,
+// grouped by the next level of detail and sorted by the cost difference.
+func costSelectorQuery(ceq *cloudcostexplorer.URLQuery, selector map[string]string) *cloudcostexplorer.URLQuery {
+	ret := ceq.Clone().Remove("pivot", "pivotidx", "tree")
+	for key := range ret.Params() {
+		if strings.HasPrefix(key, "group") {
+			ret.Remove(key)
+		}
+	}
+	ret.Set("group1", "SERVICE").
+		Set("showdiff", "1").
+		Set("showdiffpct", "1").
+		Set("sort", "diff")
+	for id, value := range selector {
+		switch id {
+		case "TAG", "COST_CATEGORY":
+			ret.Add(fmt.Sprintf("f%s", id), value)
+		default:
+			ret.Set(fmt.Sprintf("f%s", id), value)
+		}
+	}
+	if _, ok := selector["USAGE_TYPE"]; ok {
+		ret.Set("group1", "OPERATION")
+	} else if _, ok := selector["SERVICE"]; ok {
+		ret.Set("group1", "USAGE_TYPE")
+	}
+	return ret
+}
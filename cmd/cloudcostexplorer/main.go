@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
-	"net/url"
 
+	"github.com/rrgmc/cloudcostexplorer"
+	aws2 "github.com/rrgmc/cloudcostexplorer/cloud/aws"
 	"github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
 )
 
@@ -23,31 +25,49 @@ func run(ctx context.Context) error {
 		return err
 	}
 
-	http.HandleFunc("/", handlerHome(config))
-	for key, value := range config {
+	logger, err := config.Logger()
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	clouds := map[string]cloudcostexplorer.Cloud{}
+	for key, value := range config.Clouds {
 		if value.Disabled {
 			continue
 		}
 
-		cloud, err := CreateCloud(ctx, value)
+		location, err := value.Location()
+		if err != nil {
+			return fmt.Errorf("failed to load timezone for %s: %w", key, err)
+		}
+		cloud, err := CreateCloud(ctx, value, location, logger.With("config", key))
 		if err != nil {
 			return fmt.Errorf("failed to create cloud for %s: %w", key, err)
 		}
-		http.Handle(fmt.Sprintf("/costexplorer/%s", url.PathEscape(key)), handlerCostExplorer(key, cloud))
+		clouds[key] = cloud
+		http.Handle(pagePath("costexplorer", key), withAPICallCounter(handlerCostExplorer(key, cloud, location)))
+		if awsCloud, ok := cloud.(*aws2.Cloud); ok {
+			http.Handle(pagePath("commitments", key), withAPICallCounter(handlerCommitments(key, awsCloud, location)))
+			http.Handle(pagePath("recommendations", key), withAPICallCounter(handlerRecommendations(key, awsCloud)))
+			http.Handle(pagePath("anomalies", key), withAPICallCounter(handlerAnomalies(key, awsCloud, location)))
+			http.Handle(pagePath("budgets", key), withAPICallCounter(handlerBudgets(key, awsCloud)))
+			http.Handle(pagePath("explain", key), withAPICallCounter(handlerExplain(key, awsCloud, location)))
+		}
 	}
+	http.HandleFunc("/", handlerHome(clouds))
 
-	fmt.Printf("http server listening at http://localhost:3335\n")
+	slog.Info("http server listening", "url", "http://localhost:3335")
 	return http.ListenAndServe(":3335", nil)
 }
 
-func handlerHome(config Config) http.HandlerFunc {
+func handlerHome(clouds map[string]cloudcostexplorer.Cloud) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		out := ui.NewHTTPOutput(w)
-		for key, value := range config {
-			if value.Disabled {
-				continue
+		for key, cloud := range clouds {
+			for _, page := range cloudPages(key, cloud) {
+				out.Writef(`<a href="%s">%s (%s)</a><br/>`, page.path, page.title, key)
 			}
-			out.Writef(`<a href="/costexplorer/%s">Cost explorer (%s)</a><br/>`, key, url.PathEscape(key))
 		}
 	})
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rrgmc/cloudcostexplorer"
+	aws2 "github.com/rrgmc/cloudcostexplorer/cloud/aws"
+	ui2 "github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
+)
+
+// cloudPage is a page available for a config item.
+type cloudPage struct {
+	path  string
+	title string
+}
+
+// pagePath returns the URL path of a page for a config item.
+func pagePath(page string, item string) string {
+	return fmt.Sprintf("/%s/%s", page, url.PathEscape(item))
+}
+
+// cloudPages returns the pages available for the cloud, the first one is always the cost explorer.
+func cloudPages(item string, cloud cloudcostexplorer.Cloud) []cloudPage {
+	ret := []cloudPage{
+		{path: pagePath("costexplorer", item), title: "Cost explorer"},
+	}
+	if _, ok := cloud.(*aws2.Cloud); ok {
+		ret = append(ret,
+			cloudPage{path: pagePath("commitments", item), title: "Commitments"},
+			cloudPage{path: pagePath("recommendations", item), title: "Purchase recommendations"},
+			cloudPage{path: pagePath("anomalies", item), title: "Anomalies"},
+			cloudPage{path: pagePath("budgets", item), title: "Budgets"},
+		)
+	}
+	return ret
+}
+
+// writePagesMenu outputs a menu with links to the other pages of the config item, if there are any.
+func writePagesMenu(out *ui2.HTTPOutput, item string, cloud cloudcostexplorer.Cloud) {
+	pages := cloudPages(item, cloud)
+	if len(pages) < 2 {
+		return
+	}
+	out.NavDropdownBegin("Pages")
+	for _, page := range pages {
+		out.NavDropdownItem(page.title, page.path)
+	}
+	out.NavDropdownEnd()
+}
+
+// withAPICallCounter counts the billable API calls made by the handler, to be shown by [writePageFooter].
+func withAPICallCounter(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := cloudcostexplorer.ContextWithAPICallCounter(r.Context(), &cloudcostexplorer.APICallCounter{})
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// writePageFooter outputs the billable API calls made by the page and in the day, if the cloud counts them.
+func writePageFooter(out *ui2.HTTPOutput, r *http.Request, cloud cloudcostexplorer.Cloud) {
+	callStats, ok := cloud.(cloudcostexplorer.CloudCallStats)
+	if !ok {
+		return
+	}
+	var pageCalls int64
+	if counter := cloudcostexplorer.APICallCounterFromContext(r.Context()); counter != nil {
+		pageCalls = counter.Count()
+	}
+	stats := callStats.CallStats()
+
+	footer := fmt.Sprintf("Billable API calls: %s on this page, %s today (UTC)",
+		humanize.Comma(pageCalls), humanize.Comma(stats.Count))
+	if stats.Budget > 0 {
+		footer += fmt.Sprintf(" of a daily budget of %s", humanize.Comma(stats.Budget))
+	}
+	if stats.Exhausted() {
+		footer += ` <span class="badge bg-danger">budget exhausted, extra output is skipped</span>`
+	}
+	out.Footer(footer)
+}
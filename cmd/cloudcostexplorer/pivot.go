@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rrgmc/cloudcostexplorer"
+	ui2 "github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
+)
+
+// writePivotTable outputs the pivot table of the query result, which must have been requested with
+// [cloudcostexplorer.WithQueryHandlerPivot].
+func writePivotTable(r *http.Request, w http.ResponseWriter, out *ui2.HTTPOutput, cloud cloudcostexplorer.Cloud,
+	uq *cloudcostexplorer.URLQuery, queryData *cloudcostexplorer.QueryResult, limit int, mincost int,
+	search string) error {
+	pivot := queryData.Pivot
+
+	if pivot.ColumnGroup != nil && len(queryData.Periods) > 1 {
+		out.Writeln(`<p>Values from period:`)
+		for periodIdx, period := range queryData.Periods {
+			btnClass := "btn-outline-secondary"
+			if periodIdx == pivot.PeriodIdx {
+				btnClass = "btn-secondary"
+			}
+			out.Writef(` <a class="btn btn-sm %s" href="%s">%s</a>`, btnClass,
+				uq.Clone().Set("pivotidx", fmt.Sprintf("%d", periodIdx)),
+				period.Format(queryData.PeriodsMultipleYears(), !queryData.PeriodsSameDuration))
+		}
+		out.Writeln(`</p>`)
+	}
+
+	out.Writeln(`<div class="table-responsive"><table class="table table-striped table-bordered table-sm">`)
+
+	// HEADER BEGIN
+	out.Writef(`<thead><tr>`)
+	out.Writeln(`<th></th>`)
+	for gidx, currentgroup := range pivot.RowGroups {
+		out.Writef(`<th>%s (%d)</th>`, currentgroup.Title(true), gidx+1)
+	}
+	for _, column := range pivot.Columns {
+		if column.Period != nil {
+			out.Writef(`<th>%s</th>`, column.Period.Format(queryData.PeriodsMultipleYears(), !queryData.PeriodsSameDuration))
+			continue
+		}
+		ov, err := itemKeyOutput(r, w, cloud, uq, queryData.Groups, len(queryData.Groups)-1, column.Key, false)
+		if err != nil {
+			return err
+		}
+		out.Writef(`<th>%s</th>`, ov)
+	}
+	out.Writeln(`<th>Total</th>`)
+	out.Writeln(`</tr></thead>`)
+	// HEADER END
+
+	out.Writeln(`<tbody>`)
+
+	// TOTAL BEGIN
+	out.Writef("<tr><td align=\"center\">%s</td><td colspan=\"%d\"><strong>TOTAL</strong></td>",
+		humanize.Comma(int64(len(pivot.Rows))),
+		len(pivot.RowGroups))
+	for _, columnTotal := range pivot.ColumnTotals {
+		out.Writef(`<td align="right"><strong>%s</strong></td>`, cloudcostexplorer.FormatMoney(columnTotal))
+	}
+	out.Writef(`<td align="right"><strong>%s</strong></td>`, cloudcostexplorer.FormatMoney(pivot.TotalValue))
+	out.Writeln("</tr>")
+	// TOTAL END
+
+	// DATA BEGIN
+	isLimit := false
+	ct := 1
+	var skipMinCost int
+	var skipSearch int
+	totalCols := 2 + len(pivot.RowGroups) + len(pivot.Columns)
+
+	for _, row := range pivot.Rows {
+		if search != "" && !(&cloudcostexplorer.Item{Keys: row.Keys}).Search(search) {
+			skipSearch++
+			continue
+		}
+
+		maxCostValue := math.Abs(row.TotalValue)
+		for _, value := range row.Values {
+			if math.Abs(value) > maxCostValue {
+				maxCostValue = math.Abs(value)
+			}
+		}
+		if mincost > 0 && maxCostValue < float64(mincost) {
+			skipMinCost++
+			continue
+		}
+
+		out.Writeln(`<tr>`)
+
+		out.Writef(`<td align="center">%d</td>`, ct)
+
+		for groupIdx, key := range row.Keys {
+			ov, err := itemKeyOutput(r, w, cloud, uq, queryData.Groups, groupIdx, key, false)
+			if err != nil {
+				return err
+			}
+			out.Writef(`<td>%s</td>`, ov)
+		}
+		for _, value := range row.Values {
+			out.Writef(`<td align="right">%s</td>`, cloudcostexplorer.FormatMoney(value))
+		}
+		out.Writef(`<td align="right"><strong>%s</strong></td>`, cloudcostexplorer.FormatMoney(row.TotalValue))
+
+		out.Writeln(`</tr>`)
+
+		ct++
+		if limit > 0 && ct > limit {
+			isLimit = true
+			break
+		}
+	}
+
+	// DATA END
+
+	if skipSearch > 0 {
+		out.Writeln(`<tr>`)
+		out.Writef(`<td colspan="%d" align="center">Skipped %s rows because of search term '%s'</td>`,
+			totalCols,
+			humanize.Comma(int64(skipSearch)),
+			search)
+		out.Writeln(`</tr>`)
+	}
+	if skipMinCost > 0 {
+		out.Writeln(`<tr>`)
+		out.Writef(`<td colspan="%d" align="center">Skipped %d rows with absolute cost less than %s [<a href="%s">remove limit</a>]</td>`,
+			totalCols,
+			skipMinCost, cloudcostexplorer.FormatMoney(float64(mincost)),
+			uq.Clone().Set("mincost", "0"))
+		out.Writeln(`</tr>`)
+	}
+	if isLimit {
+		out.Writeln(`<tr>`)
+		out.Writef(`<td colspan="%d" align="center">Stopped after reaching limit of %s (total was %s) [use "&amp;limit=5000" to increase limit]</td>`,
+			totalCols,
+			humanize.Comma(int64(limit)),
+			humanize.Comma(int64(len(pivot.Rows))))
+		out.Writeln(`</tr>`)
+	}
+
+	out.Writeln(`</tbody></table></div>`)
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"html"
+
+	"github.com/rrgmc/cloudcostexplorer"
+	ui2 "github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
+)
+
+// writeQueryInfo outputs a collapsed section with the queries executed by the cloud, with their parameters and
+// statistics, and a button to copy each query ready to be run.
+func writeQueryInfo(out *ui2.HTTPOutput, queryInfo []cloudcostexplorer.QueryInfo) {
+	if len(queryInfo) == 0 {
+		return
+	}
+
+	out.Writeln(`<p class="mt-3"><button class="btn btn-sm btn-outline-secondary" type="button" data-bs-toggle="collapse"
+data-bs-target="#queryinfo" aria-expanded="false" aria-controls="queryinfo"><i class="bi bi-bug"></i> Query inspector</button></p>`)
+	out.Writeln(`<div class="collapse" id="queryinfo">`)
+	for idx, info := range queryInfo {
+		queryID := fmt.Sprintf("queryinfo-%d", idx+1)
+		copyID := queryID
+		if info.CopyQuery != "" {
+			copyID = fmt.Sprintf("%s-copy", queryID)
+		}
+		copyTitle := "Copy"
+		if info.IsSQL {
+			copyTitle = "Copy SQL"
+		}
+
+		out.Writeln(`<div class="card mb-3"><div class="card-header d-flex justify-content-between align-items-center">`)
+		out.Writef(`<strong>%s</strong>`, html.EscapeString(info.Title))
+		out.Writef(`<button class="btn btn-sm btn-outline-primary" type="button"
+onclick="navigator.clipboard.writeText(document.getElementById('%s').textContent)"><i class="bi bi-clipboard"></i> %s</button>`,
+			copyID, copyTitle)
+		out.Writeln(`</div><div class="card-body">`)
+		out.Writef(`<pre class="bg-light border p-2 small" id="%s">%s</pre>`+"\n", queryID, html.EscapeString(info.Query))
+		if info.CopyQuery != "" {
+			// the copied query, with the parameters inlined.
+			out.Writef(`<pre hidden id="%s">%s</pre>`+"\n", copyID, html.EscapeString(info.CopyQuery))
+		}
+		writeQueryInfoValues(out, "Parameters", info.Parameters)
+		writeQueryInfoValues(out, "Statistics", info.Stats)
+		out.Writeln(`</div></div>`)
+	}
+	out.Writeln(`</div>`)
+}
+
+func writeQueryInfoValues(out *ui2.HTTPOutput, title string, values []cloudcostexplorer.QueryInfoValue) {
+	if len(values) == 0 {
+		return
+	}
+	out.Writef(`<h6>%s</h6>`+"\n", title)
+	out.Writeln(`<table class="table table-sm table-bordered small w-auto"><tbody>`)
+	for _, value := range values {
+		out.Writef(`<tr><th>%s</th><td><code>%s</code></td></tr>`+"\n", html.EscapeString(value.Name),
+			html.EscapeString(value.Value))
+	}
+	out.Writeln(`</tbody></table>`)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/dustin/go-humanize"
+	"github.com/rrgmc/cloudcostexplorer"
+	aws2 "github.com/rrgmc/cloudcostexplorer/cloud/aws"
+	ui2 "github.com/rrgmc/cloudcostexplorer/cmd/cloudcostexplorer/ui"
+)
+
+// handlerRecommendations shows the AWS Savings Plans and reservation purchase recommendations.
+func handlerRecommendations(item string, cloud *aws2.Cloud) http.Handler {
+	return ui2.HTTPHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		rootPath := pagePath("recommendations", item)
+
+		uq := cloudcostexplorer.NewURLQuery(rootPath)
+
+		// parameters
+		query := aws2.PurchaseRecommendationQuery{
+			SavingsPlansType:   types.SupportedSavingsPlansTypeComputeSp,
+			ReservationService: aws2.ServiceEC2,
+			Term:               types.TermInYearsOneYear,
+			PaymentOption:      types.PaymentOptionNoUpfront,
+			LookbackPeriod:     types.LookbackPeriodInDaysThirtyDays,
+		}
+
+		if value, paramExists := HTTPQueryStringValue(r, "sptype", ""); paramExists {
+			query.SavingsPlansType = types.SupportedSavingsPlansType(value)
+			if !slices.Contains(query.SavingsPlansType.Values(), query.SavingsPlansType) {
+				return fmt.Errorf("invalid savings plans type '%s'", value)
+			}
+			uq.Set("sptype", value)
+		}
+		if value, paramExists := HTTPQueryStringValue(r, "service", ""); paramExists {
+			if !slices.Contains(aws2.ReservationServices, value) {
+				return fmt.Errorf("invalid reservation service '%s'", value)
+			}
+			query.ReservationService = value
+			uq.Set("service", value)
+		}
+		if value, paramExists := HTTPQueryStringValue(r, "term", ""); paramExists {
+			query.Term = types.TermInYears(value)
+			if !slices.Contains(query.Term.Values(), query.Term) {
+				return fmt.Errorf("invalid term '%s'", value)
+			}
+			uq.Set("term", value)
+		}
+		if value, paramExists := HTTPQueryStringValue(r, "payment", ""); paramExists {
+			query.PaymentOption = types.PaymentOption(value)
+			if !slices.Contains(recommendationPaymentOptions, query.PaymentOption) {
+				return fmt.Errorf("invalid payment option '%s'", value)
+			}
+			uq.Set("payment", value)
+		}
+		if value, paramExists := HTTPQueryStringValue(r, "lookback", ""); paramExists {
+			query.LookbackPeriod = types.LookbackPeriodInDays(value)
+			if !slices.Contains(query.LookbackPeriod.Values(), query.LookbackPeriod) {
+				return fmt.Errorf("invalid lookback period '%s'", value)
+			}
+			uq.Set("lookback", value)
+		}
+
+		recommendations := cloud.PurchaseRecommendations(r.Context(), query)
+
+		out := ui2.NewHTTPOutput(w)
+
+		out.DocBegin(fmt.Sprintf("%s - Recommendations - CloudCostExplorer", item))
+
+		out.NavBegin(rootPath)
+		out.NavMenuBegin()
+
+		out.NavDropdownBegin("Savings plans type")
+		for _, value := range query.SavingsPlansType.Values() {
+			out.NavDropdownItem(enumTitle(string(value)), uq.Clone().Set("sptype", string(value)).String())
+		}
+		out.NavDropdownEnd()
+
+		out.NavDropdownBegin("Reservation service")
+		for _, value := range aws2.ReservationServices {
+			out.NavDropdownItem(value, uq.Clone().Set("service", value).String())
+		}
+		out.NavDropdownEnd()
+
+		out.NavDropdownBegin("Term")
+		for _, value := range query.Term.Values() {
+			out.NavDropdownItem(enumTitle(string(value)), uq.Clone().Set("term", string(value)).String())
+		}
+		out.NavDropdownEnd()
+
+		out.NavDropdownBegin("Payment")
+		for _, value := range recommendationPaymentOptions {
+			out.NavDropdownItem(enumTitle(string(value)), uq.Clone().Set("payment", string(value)).String())
+		}
+		out.NavDropdownEnd()
+
+		out.NavDropdownBegin("Lookback")
+		for _, value := range query.LookbackPeriod.Values() {
+			out.NavDropdownItem(enumTitle(string(value)), uq.Clone().Set("lookback", string(value)).String())
+		}
+		out.NavDropdownEnd()
+
+		writePagesMenu(out, item, cloud)
+
+		out.NavMenuEnd()
+
+		out.NavTextCustom(`<span class="badge bg-secondary">Term</span>`, enumTitle(string(query.Term)))
+		out.NavTextCustom(`<span class="badge bg-secondary">Payment</span>`, enumTitle(string(query.PaymentOption)))
+		out.NavTextCustom(`<span class="badge bg-secondary">Lookback</span>`, enumTitle(string(query.LookbackPeriod)))
+
+		out.NavEnd()
+
+		out.BodyBegin()
+
+		out.Writef(`<h3>Savings Plans (%s)</h3>`, enumTitle(string(query.SavingsPlansType)))
+		writePurchaseRecommendations(out, item, recommendations.LookbackDays, true,
+			recommendations.SavingsPlans, recommendations.SavingsPlansErr)
+
+		out.Writef(`<h3>Reserved Instances (%s)</h3>`, query.ReservationService)
+		writePurchaseRecommendations(out, item, recommendations.LookbackDays, false,
+			recommendations.Reservations, recommendations.ReservationsErr)
+
+		out.BodyEnd()
+
+		writePageFooter(out, r, cloud)
+
+		out.DocEnd()
+
+		return nil
+	})
+}
+
+// recommendationPaymentOptions are the payment options supported by the purchase recommendations.
+var recommendationPaymentOptions = []types.PaymentOption{
+	types.PaymentOptionNoUpfront,
+	types.PaymentOptionPartialUpfront,
+	types.PaymentOptionAllUpfront,
+}
+
+// writePurchaseRecommendations outputs a table of purchase recommendations, each one linking to the cost explorer
+// showing the usage of the lookback period.
+func writePurchaseRecommendations(out *ui2.HTTPOutput, item string, lookbackDays int, isSavingsPlans bool,
+	recommendations []*aws2.PurchaseRecommendation, err error) {
+	if err != nil {
+		out.Writef(`<p>error: %s</p>`, err.Error())
+		return
+	}
+	if len(recommendations) == 0 {
+		out.Writeln(`<p>No recommendations.</p>`)
+		return
+	}
+
+	var totalSavings float64
+	for _, recommendation := range recommendations {
+		totalSavings += recommendation.EstimatedMonthlySavings
+	}
+
+	quantityTitle := "Instances"
+	if isSavingsPlans {
+		quantityTitle = "Hourly commitment"
+	}
+
+	out.Writef(`<p>Total estimated monthly savings: <strong>%s</strong></p>`, cloudcostexplorer.FormatMoney(totalSavings))
+
+	out.Writeln(`<div class="table-responsive"><table class="table table-striped table-bordered table-sm">`)
+	out.Writef(`<thead><tr><th>Account</th><th>Service</th><th>Instance family</th><th>Instance type</th><th>Region</th>
+<th>%s</th><th>Upfront cost</th><th>Est. monthly savings</th><th>Savings %%</th><th>Break-even</th><th></th></tr></thead>`,
+		quantityTitle)
+	out.Writeln(`<tbody>`)
+	for _, recommendation := range recommendations {
+		quantity := humanize.CommafWithDigits(recommendation.Quantity, 2)
+		if isSavingsPlans {
+			quantity = fmt.Sprintf("%s/h", cloudcostexplorer.FormatMoney(recommendation.HourlyCommitment))
+		}
+		breakEven := "immediate"
+		if recommendation.BreakEvenMonths > 0 {
+			breakEven = fmt.Sprintf("%s months", humanize.CommafWithDigits(recommendation.BreakEvenMonths, 1))
+		}
+
+		out.Writeln(`<tr>`)
+		out.Writef(`<td>%s</td>`, recommendation.AccountID)
+		out.Writef(`<td>%s</td>`, recommendation.Service)
+		out.Writef(`<td>%s</td>`, recommendation.InstanceFamily)
+		out.Writef(`<td>%s</td>`, recommendation.InstanceType)
+		out.Writef(`<td>%s</td>`, recommendation.Region)
+		out.Writef(`<td align="right">%s</td>`, quantity)
+		out.Writef(`<td align="right">%s</td>`, cloudcostexplorer.FormatMoney(recommendation.UpfrontCost))
+		out.Writef(`<td align="right"><strong>%s</strong></td>`, cloudcostexplorer.FormatMoney(recommendation.EstimatedMonthlySavings))
+		out.Writef(`<td align="right">%s%%</td>`, humanize.CommafWithDigits(recommendation.EstimatedSavingsPercentage, 2))
+		out.Writef(`<td align="right">%s</td>`, breakEven)
+		out.Writef(`<td><a href="%s">Usage</a></td>`, purchaseRecommendationUsageQuery(item, lookbackDays, recommendation))
+		out.Writeln(`</tr>`)
+	}
+	out.Writeln(`</tbody></table></div>`)
+}
+
+// purchaseRecommendationUsageQuery returns the cost explorer query showing the usage related to the recommendation
+// during the lookback period.
+func purchaseRecommendationUsageQuery(item string, lookbackDays int, recommendation *aws2.PurchaseRecommendation) *cloudcostexplorer.URLQuery {
+	ret := cloudcostexplorer.NewURLQuery(pagePath("costexplorer", item)).
+		Set("period", fmt.Sprintf("d%d", lookbackDays)).
+		Set("group1", "SERVICE").
+		Set("group2", "REGION")
+	if recommendation.AccountID != "" {
+		ret.Set("fLINKED_ACCOUNT", recommendation.AccountID)
+	}
+	if recommendation.Service != "" {
+		ret.Set("fSERVICE", recommendation.Service).
+			Set("group1", "INSTANCE_TYPE")
+	}
+	// the instance family is only a cost explorer dimension for EC2 and RDS. The recommendation region is a
+	// description and not the region code, so it can't be used as a filter.
+	if recommendation.InstanceFamily != "" &&
+		(recommendation.Service == aws2.ServiceEC2 || recommendation.Service == aws2.ServiceRDS) {
+		ret.Set("fINSTANCE_TYPE_FAMILY", recommendation.InstanceFamily)
+	}
+	return ret
+}
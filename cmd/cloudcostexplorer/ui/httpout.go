@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"html"
 	"net/http"
@@ -131,11 +132,18 @@ func (out *HTTPOutput) BodyEnd() {
 	out.Writeln(`</div></div>`)
 }
 
+// Footer outputs a footer line after the body.
+func (out *HTTPOutput) Footer(s string) {
+	out.Writef(`<footer class="border-top mt-3 pt-2 pb-2 small text-muted">%s</footer>`+"\n", s)
+}
+
 type HTTPHandlerWithError func(http.ResponseWriter, *http.Request) error
 
 func (h HTTPHandlerWithError) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	err := h(w, r)
-	if err != nil {
+	if errors.Is(err, cloudcostexplorer.ErrAPICallBudgetExhausted) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	} else if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
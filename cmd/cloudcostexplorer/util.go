@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"strings"
 
 	"github.com/rrgmc/cloudcostexplorer"
 )
@@ -19,27 +20,78 @@ func costDiffPct(cost1, cost2 float64) float64 {
 	return costDiff
 }
 
-func itemCostDiffGet(idx int, item *cloudcostexplorer.Item) (float64, float64) {
+// costNormalization sets how the costs of periods with different durations are compared.
+type costNormalization string
+
+const (
+	costNormalizationNone  costNormalization = ""      // compare the period totals.
+	costNormalizationDaily costNormalization = "daily" // compare the average daily cost of each period.
+	costNormalizationScale costNormalization = "scale" // scale the previous period to the number of days of the current one.
+)
+
+func parseCostNormalization(value string) costNormalization {
+	switch costNormalization(value) {
+	case costNormalizationDaily, costNormalizationScale:
+		return costNormalization(value)
+	default:
+		return costNormalizationNone
+	}
+}
+
+// Title returns the description of the normalization mode.
+func (n costNormalization) Title() string {
+	switch n {
+	case costNormalizationDaily:
+		return "average daily cost"
+	case costNormalizationScale:
+		return "scaled to same days"
+	default:
+		return "totals"
+	}
+}
+
+// normalize returns the costs of the periods with indexes idx-1 and idx normalized for comparison.
+func (n costNormalization) normalize(idx int, periods []cloudcostexplorer.QueryResultPeriod, previous, current float64) (float64, float64) {
+	if idx < 1 || idx >= len(periods) {
+		return previous, current
+	}
+	previousDays, currentDays := float64(periods[idx-1].Days()), float64(periods[idx].Days())
+	switch n {
+	case costNormalizationDaily:
+		return previous / previousDays, current / currentDays
+	case costNormalizationScale:
+		return previous * currentDays / previousDays, current
+	default:
+		return previous, current
+	}
+}
+
+func costDiffGet(idx int, periods []cloudcostexplorer.QueryResultPeriod, normalization costNormalization, previous, current float64) (float64, float64) {
+	previous, current = normalization.normalize(idx, periods, previous, current)
+	return current - previous, costDiffPct(current, previous)
+}
+
+func itemCostDiffGet(idx int, item *cloudcostexplorer.Item, periods []cloudcostexplorer.QueryResultPeriod, normalization costNormalization) (float64, float64) {
 	if idx < 1 || idx >= len(item.Values) {
 		return 0, 0
 	}
-	return item.Values[idx] - item.Values[idx-1], costDiffPct(item.Values[idx], item.Values[idx-1])
+	return costDiffGet(idx, periods, normalization, item.Values[idx-1], item.Values[idx])
 }
 
-func periodCostDiffGet(idx int, items []cloudcostexplorer.QueryResultPeriod) (float64, float64) {
+func periodCostDiffGet(idx int, items []cloudcostexplorer.QueryResultPeriod, normalization costNormalization) (float64, float64) {
 	if idx < 1 || idx >= len(items) {
 		return 0, 0
 	}
-	return items[idx].TotalValue - items[idx-1].TotalValue, costDiffPct(items[idx].TotalValue, items[idx-1].TotalValue)
+	return costDiffGet(idx, items, normalization, items[idx-1].TotalValue, items[idx].TotalValue)
 }
 
-func itemCostDiff(idx int, item *cloudcostexplorer.Item) float64 {
-	d, _ := itemCostDiffGet(idx, item)
+func itemCostDiff(idx int, item *cloudcostexplorer.Item, periods []cloudcostexplorer.QueryResultPeriod, normalization costNormalization) float64 {
+	d, _ := itemCostDiffGet(idx, item, periods, normalization)
 	return d
 }
 
-func itemCostDiffPct(idx int, item *cloudcostexplorer.Item) float64 {
-	_, d := itemCostDiffGet(idx, item)
+func itemCostDiffPct(idx int, item *cloudcostexplorer.Item, periods []cloudcostexplorer.QueryResultPeriod, normalization costNormalization) float64 {
+	_, d := itemCostDiffGet(idx, item, periods, normalization)
 	return d
 }
 
@@ -54,6 +106,19 @@ type activeFilter struct {
 	parameter  cloudcostexplorer.Parameter
 	title      string
 	paramNames []string
+	paramValue string // if set, only this value is removed from the parameters, for filters that can be repeated.
+}
+
+// removeQuery returns a copy of the query without this filter.
+func (f activeFilter) removeQuery(uq *cloudcostexplorer.URLQuery) *cloudcostexplorer.URLQuery {
+	ret := uq.Clone()
+	if f.paramValue == "" {
+		return ret.Remove(f.paramNames...)
+	}
+	for _, paramName := range f.paramNames {
+		ret.RemoveValue(paramName, f.paramValue)
+	}
+	return ret
 }
 
 type valueContext struct {
@@ -84,3 +149,12 @@ func (v valueContext) GroupParamName() string {
 func (v valueContext) FilterParamName(id string) string {
 	return fmt.Sprintf("f%s", id)
 }
+
+// enumTitle returns a title for an API enum value like "ONE_YEAR" ("One year").
+func enumTitle(value string) string {
+	ret := strings.ToLower(strings.ReplaceAll(value, "_", " "))
+	if ret == "" {
+		return ret
+	}
+	return strings.ToUpper(ret[:1]) + ret[1:]
+}
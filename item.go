@@ -6,8 +6,9 @@ import (
 
 // Item contains the keys and values of a single cost explorer item, based on the query groups.
 type Item struct {
-	Keys   []ItemKey
-	Values []float64
+	Keys    []ItemKey
+	Values  []float64
+	Credits []float64 // the credits included in Values, if the cloud supports it. Usually negative values.
 }
 
 func NewItem(keys []ItemKey, periods int) *Item {
@@ -16,10 +17,30 @@ func NewItem(keys []ItemKey, periods int) *Item {
 	}
 	for range periods {
 		ret.Values = append(ret.Values, 0.0)
+		ret.Credits = append(ret.Credits, 0.0)
 	}
 	return ret
 }
 
+// Add adds the values of another item to this one.
+func (i *Item) Add(other *Item) {
+	for idx, value := range other.Values {
+		if idx < len(i.Values) {
+			i.Values[idx] += value
+		}
+	}
+	for idx, value := range other.Credits {
+		if idx < len(i.Credits) {
+			i.Credits[idx] += value
+		}
+	}
+}
+
+// GrossValue returns the value of the period before credits were applied.
+func (i *Item) GrossValue(periodIdx int) float64 {
+	return i.Values[periodIdx] - i.Credits[periodIdx]
+}
+
 // Search returns whether the search string is contained on any item key value.
 func (i *Item) Search(search string) bool {
 	sv := strings.ToLower(search)
@@ -0,0 +1,85 @@
+package cloudcostexplorer
+
+import (
+	"slices"
+)
+
+// ItemTreeNode is a node of the hierarchical view of the query items, where each tree level corresponds to one
+// query group.
+// Item contains the keys up to the node level and the subtotal values of all items below it. On leaf nodes, Item
+// is the original query item.
+type ItemTreeNode struct {
+	*Item
+	Children []*ItemTreeNode
+}
+
+// Level returns the group index of the node.
+func (n *ItemTreeNode) Level() int {
+	return len(n.Keys) - 1
+}
+
+// IsLeaf returns whether the node is a leaf node, containing an original query item.
+func (n *ItemTreeNode) IsLeaf() bool {
+	return len(n.Children) == 0
+}
+
+// Leaves returns all the leaf nodes below this node, or the node itself if it is a leaf.
+func (n *ItemTreeNode) Leaves() []*ItemTreeNode {
+	if n.IsLeaf() {
+		return []*ItemTreeNode{n}
+	}
+	var ret []*ItemTreeNode
+	for _, child := range n.Children {
+		ret = append(ret, child.Leaves()...)
+	}
+	return ret
+}
+
+// NewItemTree builds a tree from a list of items, grouping by the first key, then by the second key, and so on,
+// calculating the subtotals of each level.
+func NewItemTree(items []*Item, periods int) []*ItemTreeNode {
+	return newItemTreeLevel(items, periods, 0)
+}
+
+func newItemTreeLevel(items []*Item, periods int, level int) []*ItemTreeNode {
+	var ret []*ItemTreeNode
+	nodes := map[string]*ItemTreeNode{}
+	nodeItems := map[string][]*Item{}
+
+	for _, item := range items {
+		if len(item.Keys) <= level+1 {
+			ret = append(ret, &ItemTreeNode{
+				Item: item,
+			})
+			continue
+		}
+
+		keyID := item.Keys[level].ID
+		node, ok := nodes[keyID]
+		if !ok {
+			node = &ItemTreeNode{
+				Item: NewItem(slices.Clone(item.Keys[:level+1]), periods),
+			}
+			nodes[keyID] = node
+			ret = append(ret, node)
+		}
+		node.Add(item)
+		nodeItems[keyID] = append(nodeItems[keyID], item)
+	}
+
+	for keyID, node := range nodes {
+		node.Children = newItemTreeLevel(nodeItems[keyID], periods, level+1)
+	}
+
+	return ret
+}
+
+// SortItemTree sorts each level of the tree using the passed compare function.
+func SortItemTree(nodes []*ItemTreeNode, cmp func(a, b *Item) int) {
+	slices.SortFunc(nodes, func(a, b *ItemTreeNode) int {
+		return cmp(a.Item, b.Item)
+	})
+	for _, node := range nodes {
+		SortItemTree(node.Children, cmp)
+	}
+}
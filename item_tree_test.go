@@ -0,0 +1,183 @@
+package cloudcostexplorer
+
+import (
+	"cmp"
+	"context"
+	"testing"
+)
+
+// testItem creates an item with one key for each ID, and one value for each period.
+func testItem(keyIDs []string, values ...float64) *Item {
+	item := NewItem(nil, len(values))
+	for _, keyID := range keyIDs {
+		item.Keys = append(item.Keys, ItemKey{ID: keyID, Value: keyID})
+	}
+	copy(item.Values, values)
+	return item
+}
+
+func TestNewItemTree(t *testing.T) {
+	items := []*Item{
+		testItem([]string{"compute", "us"}, 1, 10),
+		testItem([]string{"storage", "us"}, 2, 20),
+		testItem([]string{"compute", "eu"}, 3, 30),
+		testItem([]string{"storage", "eu"}, 4, 40),
+		testItem([]string{"network", "us"}, 5, 50),
+	}
+
+	tree := NewItemTree(items, 2)
+
+	// the nodes are kept in the order the keys first appear.
+	expected := []struct {
+		keyID    string
+		values   []float64
+		children []string
+	}{
+		{keyID: "compute", values: []float64{4, 40}, children: []string{"us", "eu"}},
+		{keyID: "storage", values: []float64{6, 60}, children: []string{"us", "eu"}},
+		{keyID: "network", values: []float64{5, 50}, children: []string{"us"}},
+	}
+	if len(tree) != len(expected) {
+		t.Fatalf("expected %d root nodes, got %d", len(expected), len(tree))
+	}
+	for nodeIdx, node := range tree {
+		exp := expected[nodeIdx]
+		if node.Keys[0].ID != exp.keyID {
+			t.Errorf("node %d: expected key '%s', got '%s'", nodeIdx, exp.keyID, node.Keys[0].ID)
+		}
+		if len(node.Keys) != 1 || node.Level() != 0 || node.IsLeaf() {
+			t.Errorf("node %d: expected a non-leaf node at level 0", nodeIdx)
+		}
+		for periodIdx, value := range exp.values {
+			if node.Values[periodIdx] != value {
+				t.Errorf("node %d: expected subtotal %v on period %d, got %v", nodeIdx, value, periodIdx, node.Values[periodIdx])
+			}
+		}
+		if len(node.Children) != len(exp.children) {
+			t.Fatalf("node %d: expected %d children, got %d", nodeIdx, len(exp.children), len(node.Children))
+		}
+		for childIdx, child := range node.Children {
+			if child.Keys[1].ID != exp.children[childIdx] {
+				t.Errorf("node %d child %d: expected key '%s', got '%s'", nodeIdx, childIdx, exp.children[childIdx], child.Keys[1].ID)
+			}
+			if !child.IsLeaf() || child.Level() != 1 || child.Keys[0].ID != exp.keyID {
+				t.Errorf("node %d child %d: expected a leaf node at level 1 below its parent", nodeIdx, childIdx)
+			}
+		}
+		if leaves := node.Leaves(); len(leaves) != len(exp.children) {
+			t.Errorf("node %d: expected %d leaves, got %d", nodeIdx, len(exp.children), len(leaves))
+		}
+	}
+
+	// leaf nodes are the original items.
+	if tree[0].Children[0].Item != items[0] {
+		t.Error("expected the leaf node to be the original item")
+	}
+}
+
+func TestNewItemTreeNesting(t *testing.T) {
+	items := []*Item{
+		testItem([]string{"compute", "us", "prod"}, 1),
+		testItem([]string{"compute", "us", "dev"}, 2),
+		testItem([]string{"compute", "eu", "prod"}, 4),
+		testItem([]string{"storage", "us", "prod"}, 8),
+	}
+
+	tree := NewItemTree(items, 1)
+
+	if len(tree) != 2 {
+		t.Fatalf("expected 2 root nodes, got %d", len(tree))
+	}
+	compute := tree[0]
+	if compute.Values[0] != 7 {
+		t.Errorf("expected subtotal 7, got %v", compute.Values[0])
+	}
+	if len(compute.Children) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(compute.Children))
+	}
+	computeUS := compute.Children[0]
+	if computeUS.Level() != 1 || computeUS.Values[0] != 3 || len(computeUS.Children) != 2 {
+		t.Errorf("expected a level 1 node with subtotal 3 and 2 children, got level %d subtotal %v with %d children",
+			computeUS.Level(), computeUS.Values[0], len(computeUS.Children))
+	}
+	if len(compute.Leaves()) != 3 {
+		t.Errorf("expected 3 leaves, got %d", len(compute.Leaves()))
+	}
+	for _, leaf := range compute.Leaves() {
+		if leaf.Level() != 2 {
+			t.Errorf("expected leaves at level 2, got %d", leaf.Level())
+		}
+	}
+	if tree[1].Values[0] != 8 {
+		t.Errorf("expected subtotal 8, got %v", tree[1].Values[0])
+	}
+}
+
+func TestSortItemTree(t *testing.T) {
+	items := []*Item{
+		testItem([]string{"compute", "us"}, 1),
+		testItem([]string{"storage", "us"}, 2),
+		testItem([]string{"compute", "eu"}, 3),
+		testItem([]string{"storage", "eu"}, 4),
+		testItem([]string{"network", "us"}, 5),
+	}
+
+	tree := NewItemTree(items, 1)
+	SortItemTree(tree, func(a, b *Item) int {
+		return cmp.Compare(b.Values[0], a.Values[0])
+	})
+
+	// storage (6), network (5), compute (4), with children sorted too.
+	expected := [][]string{
+		{"storage", "eu", "us"},
+		{"network", "us"},
+		{"compute", "eu", "us"},
+	}
+	if len(tree) != len(expected) {
+		t.Fatalf("expected %d root nodes, got %d", len(expected), len(tree))
+	}
+	for nodeIdx, node := range tree {
+		if node.Keys[0].ID != expected[nodeIdx][0] {
+			t.Errorf("node %d: expected key '%s', got '%s'", nodeIdx, expected[nodeIdx][0], node.Keys[0].ID)
+		}
+		for childIdx, child := range node.Children {
+			if child.Keys[1].ID != expected[nodeIdx][childIdx+1] {
+				t.Errorf("node %d child %d: expected key '%s', got '%s'", nodeIdx, childIdx,
+					expected[nodeIdx][childIdx+1], child.Keys[1].ID)
+			}
+		}
+	}
+}
+
+func TestQueryHandlerTree(t *testing.T) {
+	cloud := &testCloud{
+		rows: []testCloudRow{
+			{keys: map[string]string{"SERVICE": "compute", "REGION": "us"}, value: 1},
+			{keys: map[string]string{"SERVICE": "compute", "REGION": "eu"}, value: 2},
+		},
+	}
+	periodLists, _, err := ParsePeriodLists(testDate(t, "2025-03-12"), "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := []QueryHandlerOption{
+		WithQueryHandlerGroups(QueryGroup{ID: "SERVICE"}, QueryGroup{ID: "REGION"}),
+		WithQueryHandlerPeriodLists(periodLists...),
+	}
+
+	result, err := QueryHandler(context.Background(), cloud, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Tree != nil {
+		t.Error("expected no tree if not requested")
+	}
+
+	result, err = QueryHandler(context.Background(), cloud, append(options, WithQueryHandlerTree())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Tree) != 1 || result.Tree[0].Values[0] != 3 || len(result.Tree[0].Children) != 2 {
+		t.Error("expected a tree with one root node with subtotal 3 and 2 children")
+	}
+}
@@ -0,0 +1,333 @@
+package cloudcostexplorer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invzhi/timex"
+)
+
+// Period expressions are short strings describing a date range, relative to a current date when needed.
+// The supported expressions are:
+//
+//	d<N>                    the last N days, like "d14".
+//	m<N>                    the last N months, like "m3".
+//	M<YYYYMM>               a calendar month, like "M202401".
+//	I<YYYYMM>               an invoice month, like "I202401". Costs are selected by invoice month instead of usage
+//	                        date, see [WithQueryInvoiceMonth].
+//	Q<YYYY>-<Q>             a calendar quarter, like "Q2026-1".
+//	Y<YYYY>                 a calendar year, like "Y2025".
+//	W<YYYY>-<WW>            an ISO 8601 week (starting on Monday), like "W2026-14".
+//	T<YYYY-MM-DD>           a single day, like "T2024-01-01".
+//	T<YYYY-MM-DD>|<YYYY-MM-DD>  a date range, like "T2024-01-01|2024-01-31".
+//	MTD, QTD, YTD           month, quarter and year to date.
+//	LW, LM, LQ, LY          the last complete week, month, quarter and year.
+//
+// When comparing periods, the first comparison expression may also be a repeat expression:
+//
+//	R<N>                    repeats the main period N times, one before the other, with the same number of days.
+//	RW<N>, RM<N>, RQ<N>     N calendar weeks, months or quarters, the last one being the one containing the end of
+//	                        the main period.
+//	RY<N>                   year over year, the same dates of the main period on the N-1 previous years.
+//
+// N must be between 1 and 36.
+// Invoice month periods can only be repeated with RM<N> or RY<N>.
+
+// ParsePeriodValue parses a single period expression, relative to currentDate, returning the period and a
+// description of it.
+func ParsePeriodValue(period string, currentDate timex.Date) (QueryPeriod, string, error) {
+	switch period {
+	case "MTD":
+		return NewQueryPeriod(StartOfMonth(currentDate), currentDate), "month to date", nil
+	case "QTD":
+		return NewQueryPeriod(StartOfQuarter(currentDate), currentDate), "quarter to date", nil
+	case "YTD":
+		return NewQueryPeriod(StartOfYear(currentDate), currentDate), "year to date", nil
+	case "LW":
+		start, end := lastCompletePeriod(currentDate, StartOfWeek, func(start timex.Date) timex.Date {
+			return start.AddDays(6)
+		})
+		return NewQueryPeriod(start, end), fmt.Sprintf("last complete week (%s)", weekDescription(start)), nil
+	case "LM":
+		start, end := lastCompletePeriod(currentDate, StartOfMonth, EndingOfMonth)
+		return NewQueryPeriod(start, end), fmt.Sprintf("last complete month (%s)", monthDescription(start)), nil
+	case "LQ":
+		start, end := lastCompletePeriod(currentDate, StartOfQuarter, endingOfQuarter)
+		return NewQueryPeriod(start, end), fmt.Sprintf("last complete quarter (%s)", quarterDescription(start)), nil
+	case "LY":
+		start, end := lastCompletePeriod(currentDate, StartOfYear, endingOfYear)
+		return NewQueryPeriod(start, end), fmt.Sprintf("last complete year (%d)", start.Year()), nil
+	}
+
+	if strings.HasPrefix(period, "d") {
+		pperiod, perr := strconv.Atoi(strings.TrimPrefix(period, "d"))
+		if perr != nil {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'days' value '%s': %w", period, perr)
+		}
+		return NewQueryPeriod(currentDate.AddDays(-pperiod+1), currentDate), fmt.Sprintf("%d days", pperiod), nil
+	} else if strings.HasPrefix(period, "m") {
+		pperiod, perr := strconv.Atoi(strings.TrimPrefix(period, "m"))
+		if perr != nil {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'months' value '%s': %w", period, perr)
+		}
+		return NewQueryPeriod(currentDate.Add(0, -pperiod, 1), currentDate), fmt.Sprintf("%d months", pperiod), nil
+	} else if strings.HasPrefix(period, "M") {
+		if len(period) != 7 {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'month' value '%s'", period)
+		}
+
+		pyear, pyearerr := strconv.Atoi(period[1:5])
+		pmonth, pmontherr := strconv.Atoi(period[5:7])
+		if pyearerr != nil {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'month' value '%s': %w", period, pyearerr)
+		}
+		if pmontherr != nil {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'month' value '%s': %w", period, pmontherr)
+		}
+		startDate, err := timex.NewDate(pyear, pmonth, 1)
+		if err != nil {
+			return QueryPeriod{}, "", fmt.Errorf("invalid month value: %s", period)
+		}
+		return NewQueryPeriod(startDate, EndingOfMonth(startDate)), monthDescription(startDate), nil
+	} else if strings.HasPrefix(period, "I") {
+		if len(period) != 7 {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'invoice month' value '%s'", period)
+		}
+		pyear, pyearerr := strconv.Atoi(period[1:5])
+		pmonth, pmontherr := strconv.Atoi(period[5:7])
+		if pyearerr != nil || pmontherr != nil {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'invoice month' value '%s': %w", period,
+				errors.Join(pyearerr, pmontherr))
+		}
+		startDate, err := timex.NewDate(pyear, pmonth, 1)
+		if err != nil {
+			return QueryPeriod{}, "", fmt.Errorf("invalid invoice month value: %s", period)
+		}
+		return NewQueryPeriod(startDate, EndingOfMonth(startDate)), fmt.Sprintf("invoice %s", monthDescription(startDate)), nil
+	} else if strings.HasPrefix(period, "Q") {
+		pyear, pquarter, err := parseYearAndNumber(period[1:])
+		if err != nil {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'quarter' value '%s': %w", period, err)
+		}
+		if pquarter < 1 || pquarter > 4 {
+			return QueryPeriod{}, "", fmt.Errorf("invalid quarter value: %s", period)
+		}
+		startDate, err := timex.NewDate(pyear, (pquarter-1)*3+1, 1)
+		if err != nil {
+			return QueryPeriod{}, "", fmt.Errorf("invalid quarter value: %s", period)
+		}
+		return NewQueryPeriod(startDate, endingOfQuarter(startDate)), quarterDescription(startDate), nil
+	} else if strings.HasPrefix(period, "Y") {
+		pyear, perr := strconv.Atoi(strings.TrimPrefix(period, "Y"))
+		if perr != nil {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'year' value '%s': %w", period, perr)
+		}
+		startDate, err := timex.NewDate(pyear, 1, 1)
+		if err != nil {
+			return QueryPeriod{}, "", fmt.Errorf("invalid year value: %s", period)
+		}
+		return NewQueryPeriod(startDate, endingOfYear(startDate)), fmt.Sprintf("%d", pyear), nil
+	} else if strings.HasPrefix(period, "W") {
+		pyear, pweek, err := parseYearAndNumber(period[1:])
+		if err != nil {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'week' value '%s': %w", period, err)
+		}
+		startDate, err := StartOfISOWeek(pyear, pweek)
+		if err != nil {
+			return QueryPeriod{}, "", fmt.Errorf("invalid week value '%s': %w", period, err)
+		}
+		return NewQueryPeriod(startDate, startDate.AddDays(6)), weekDescription(startDate), nil
+	} else if strings.HasPrefix(period, "T") {
+		if (len(period) != 11 && len(period) != 22) || (len(period) == 22 && period[11] != '|') {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'date range' value '%s'", period)
+		}
+
+		startDate, serr := timex.ParseDate("YYYY-MM-DD", period[1:11])
+		if serr != nil {
+			return QueryPeriod{}, "", fmt.Errorf("could not parse 'date range' value '%s': %w", period, serr)
+		}
+		var endDate timex.Date
+		if len(period) == 22 {
+			endDate, serr = timex.ParseDate("YYYY-MM-DD", period[12:22])
+			if serr != nil {
+				return QueryPeriod{}, "", fmt.Errorf("could not parse 'date range' value '%s': %w", period, serr)
+			}
+			if endDate.Before(startDate) {
+				return QueryPeriod{}, "", fmt.Errorf("invalid 'date range' value '%s': end is before start", period)
+			}
+		} else {
+			endDate = startDate
+		}
+
+		if startDate.Equal(endDate) {
+			return NewQueryPeriod(startDate, endDate), startDate.Format("DD/MMM/YYYY"), nil
+		}
+		return NewQueryPeriod(startDate, endDate),
+			fmt.Sprintf("%s to %s", startDate.Format("DD/MMM/YYYY"), endDate.Format("DD/MMM/YYYY")), nil
+	}
+
+	return QueryPeriod{}, "", fmt.Errorf("unknown period value '%s'", period)
+}
+
+// IsInvoiceMonthPeriod returns whether the period expression selects an invoice month.
+func IsInvoiceMonthPeriod(period string) bool {
+	return strings.HasPrefix(period, "I")
+}
+
+// IsRepeatPeriod returns whether the period expression is a repeat expression, which can only be used as the first
+// comparison period.
+func IsRepeatPeriod(period string) bool {
+	return strings.HasPrefix(period, "R")
+}
+
+// ParsePeriodLists parses a period expression and a list of optional comparison period expressions, returning the
+// period lists to query, ordered from the oldest to the newest, and the description of the main period.
+// Comparison periods are parsed relative to the day before the start of the main period. If the first comparison
+// period is a repeat expression, the main period is repeated and the other comparison periods are ignored.
+func ParsePeriodLists(currentDate timex.Date, period string, comparePeriods ...string) ([]QueryPeriodList, string, error) {
+	qperiod, desc, err := ParsePeriodValue(period, currentDate)
+	if err != nil {
+		return nil, "", err
+	}
+
+	isInvoiceMonth := IsInvoiceMonthPeriod(period)
+
+	if len(comparePeriods) > 0 && IsRepeatPeriod(comparePeriods[0]) {
+		if isInvoiceMonth && !strings.HasPrefix(comparePeriods[0], "RM") && !strings.HasPrefix(comparePeriods[0], "RY") {
+			return nil, "", fmt.Errorf("invoice month periods can only be repeated by calendar months or years, not '%s'",
+				comparePeriods[0])
+		}
+
+		periods, rdesc, err := generateRepeatPeriods(comparePeriods[0], qperiod)
+		if err != nil {
+			return nil, "", err
+		}
+
+		desc = desc + fmt.Sprintf(" (%s)", rdesc)
+		return []QueryPeriodList{
+			{
+				Periods:      periods,
+				Description:  desc,
+				InvoiceMonth: isInvoiceMonth,
+			},
+		}, desc, nil
+	}
+
+	list := []QueryPeriodList{
+		{
+			Periods:      []QueryPeriod{qperiod},
+			Description:  desc,
+			InvoiceMonth: isInvoiceMonth,
+		},
+	}
+
+	for _, comparePeriod := range comparePeriods {
+		if comparePeriod == "" {
+			break
+		}
+		cperiod, cdesc, err := ParsePeriodValue(comparePeriod, qperiod.Start.AddDays(-1))
+		if err != nil {
+			return nil, "", err
+		}
+
+		list = slices.Insert(list, 0, QueryPeriodList{
+			Periods:      []QueryPeriod{cperiod},
+			Description:  cdesc,
+			InvoiceMonth: IsInvoiceMonthPeriod(comparePeriod),
+		})
+	}
+
+	return list, desc, nil
+}
+
+// maxRepeatAmount is the maximum number of periods of a repeat expression.
+const maxRepeatAmount = 36
+
+// generateRepeatPeriods generates the periods of a repeat expression based on the main period.
+func generateRepeatPeriods(repeat string, period QueryPeriod) ([]QueryPeriod, string, error) {
+	value := strings.TrimPrefix(repeat, "R")
+	kind := ""
+	if value != "" && strings.Contains("WMQY", value[:1]) {
+		kind, value = value[:1], value[1:]
+	}
+	amount, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not parse 'repeat' value '%s': %w", repeat, err)
+	}
+	if amount < 1 || amount > maxRepeatAmount {
+		return nil, "", fmt.Errorf("could not parse 'repeat' value '%s': amount must be between 1 and %d",
+			repeat, maxRepeatAmount)
+	}
+
+	switch kind {
+	case "W":
+		return GenerateCalendarQueryPeriods(period.End, CalendarUnitWeek, amount),
+			fmt.Sprintf("%d calendar weeks", amount), nil
+	case "M":
+		return GenerateCalendarQueryPeriods(period.End, CalendarUnitMonth, amount),
+			fmt.Sprintf("%d calendar months", amount), nil
+	case "Q":
+		return GenerateCalendarQueryPeriods(period.End, CalendarUnitQuarter, amount),
+			fmt.Sprintf("%d calendar quarters", amount), nil
+	case "Y":
+		return GenerateYearOverYearQueryPeriods(period.Start, period.End, amount),
+			fmt.Sprintf("year over year, %d years", amount), nil
+	default:
+		return GenerateQueryPeriods(period.Start, period.End, amount),
+			fmt.Sprintf("repeat %d", amount), nil
+	}
+}
+
+// lastCompletePeriod returns the last period which ends on or before currentDate.
+func lastCompletePeriod(currentDate timex.Date, startOf func(timex.Date) timex.Date,
+	endingOf func(timex.Date) timex.Date) (timex.Date, timex.Date) {
+	start := startOf(currentDate)
+	end := endingOf(start)
+	if end.Equal(currentDate) {
+		return start, end
+	}
+	start = startOf(start.AddDays(-1))
+	return start, endingOf(start)
+}
+
+// parseYearAndNumber parses values in the format "YYYY-N".
+func parseYearAndNumber(value string) (int, int, error) {
+	syear, snumber, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected format 'YYYY-N'")
+	}
+	year, err := strconv.Atoi(syear)
+	if err != nil {
+		return 0, 0, err
+	}
+	number, err := strconv.Atoi(snumber)
+	if err != nil {
+		return 0, 0, err
+	}
+	return year, number, nil
+}
+
+func endingOfQuarter(date timex.Date) timex.Date {
+	return StartOfQuarter(date).Add(0, 3, -1)
+}
+
+func endingOfYear(date timex.Date) timex.Date {
+	return StartOfYear(date).Add(1, 0, -1)
+}
+
+func monthDescription(date timex.Date) string {
+	return fmt.Sprintf("%s/%d", ShortMonthName(time.Month(date.Month())), date.Year())
+}
+
+func quarterDescription(date timex.Date) string {
+	return fmt.Sprintf("Q%d/%d", date.Quarter(), date.Year())
+}
+
+func weekDescription(start timex.Date) string {
+	year, week := start.ISOWeek()
+	return fmt.Sprintf("week %d/%d, %s", week, year, NewQueryPeriod(start, start.AddDays(6)).String())
+}
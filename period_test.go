@@ -0,0 +1,233 @@
+package cloudcostexplorer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/invzhi/timex"
+)
+
+// testDate parses a date in the YYYY-MM-DD format.
+func testDate(t *testing.T, value string) timex.Date {
+	t.Helper()
+	ret, err := timex.ParseDate("YYYY-MM-DD", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func TestParsePeriodValue(t *testing.T) {
+	currentDate := "2025-03-12" // a Wednesday.
+
+	for _, test := range []struct {
+		period      string
+		currentDate string // default is currentDate.
+		start, end  string
+	}{
+		{period: "d14", start: "2025-02-27", end: "2025-03-12"},
+		{period: "d1", start: "2025-03-12", end: "2025-03-12"},
+		{period: "m3", start: "2024-12-13", end: "2025-03-12"},
+		{period: "M202402", start: "2024-02-01", end: "2024-02-29"},
+		{period: "M202412", start: "2024-12-01", end: "2024-12-31"},
+		{period: "I202401", start: "2024-01-01", end: "2024-01-31"},
+		{period: "Q2026-1", start: "2026-01-01", end: "2026-03-31"},
+		{period: "Q2025-4", start: "2025-10-01", end: "2025-12-31"},
+		{period: "Y2025", start: "2025-01-01", end: "2025-12-31"},
+		{period: "W2026-14", start: "2026-03-30", end: "2026-04-05"},
+		{period: "W2020-53", start: "2020-12-28", end: "2021-01-03"},
+		{period: "W2025-1", start: "2024-12-30", end: "2025-01-05"},
+		{period: "T2024-01-01", start: "2024-01-01", end: "2024-01-01"},
+		{period: "T2024-01-01|2024-01-31", start: "2024-01-01", end: "2024-01-31"},
+		{period: "MTD", start: "2025-03-01", end: "2025-03-12"},
+		{period: "QTD", start: "2025-01-01", end: "2025-03-12"},
+		{period: "YTD", start: "2025-01-01", end: "2025-03-12"},
+		{period: "LW", start: "2025-03-03", end: "2025-03-09"},
+		{period: "LW", currentDate: "2025-03-09", start: "2025-03-03", end: "2025-03-09"},
+		{period: "LM", start: "2025-02-01", end: "2025-02-28"},
+		{period: "LM", currentDate: "2025-02-28", start: "2025-02-01", end: "2025-02-28"},
+		{period: "LQ", start: "2024-10-01", end: "2024-12-31"},
+		{period: "LY", start: "2024-01-01", end: "2024-12-31"},
+		{period: "LY", currentDate: "2024-12-31", start: "2024-01-01", end: "2024-12-31"},
+	} {
+		current := currentDate
+		if test.currentDate != "" {
+			current = test.currentDate
+		}
+		t.Run(test.period+"@"+current, func(t *testing.T) {
+			period, desc, err := ParsePeriodValue(test.period, testDate(t, current))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if desc == "" {
+				t.Error("expected a description")
+			}
+			if !period.Start.Equal(testDate(t, test.start)) || !period.End.Equal(testDate(t, test.end)) {
+				t.Errorf("expected %s to %s, got %s to %s", test.start, test.end, period.Start, period.End)
+			}
+		})
+	}
+}
+
+func TestParsePeriodValueInvalid(t *testing.T) {
+	for _, period := range []string{
+		"",
+		"x",
+		"dX",
+		"m",
+		"M2024",
+		"M2024013",
+		"M202413",
+		"I2024-1",
+		"I202400",
+		"Q2025",
+		"Q2025-0",
+		"Q2025-5",
+		"YX",
+		"W2026",
+		"W2026-54",
+		"W2021-53",
+		"T2024-01",
+		"T2024-13-01",
+		"T2024-01-01X2024-02-01",
+		"T2024-01-01|2024-02-3",
+		"T2024-02-01|2024-01-01",
+	} {
+		t.Run(period, func(t *testing.T) {
+			if _, _, err := ParsePeriodValue(period, testDate(t, "2025-03-12")); err == nil {
+				t.Errorf("expected an error for '%s'", period)
+			}
+		})
+	}
+}
+
+func TestParsePeriodLists(t *testing.T) {
+	type testPeriod struct {
+		start, end string
+	}
+
+	for _, test := range []struct {
+		name           string
+		period         string
+		comparePeriods []string
+		lists          [][]testPeriod // periods of each list.
+	}{
+		{
+			name:   "single period",
+			period: "M202503",
+			lists:  [][]testPeriod{{{"2025-03-01", "2025-03-31"}}},
+		},
+		{
+			name:           "comparison periods relative to the main period",
+			period:         "M202503",
+			comparePeriods: []string{"LM", "LQ"},
+			lists: [][]testPeriod{
+				{{"2024-10-01", "2024-12-31"}},
+				{{"2025-02-01", "2025-02-28"}},
+				{{"2025-03-01", "2025-03-31"}},
+			},
+		},
+		{
+			name:           "repeat",
+			period:         "d7",
+			comparePeriods: []string{"R2"},
+			lists:          [][]testPeriod{{{"2025-02-27", "2025-03-05"}, {"2025-03-06", "2025-03-12"}}},
+		},
+		{
+			name:           "repeat calendar weeks",
+			period:         "MTD",
+			comparePeriods: []string{"RW2"},
+			lists:          [][]testPeriod{{{"2025-03-03", "2025-03-09"}, {"2025-03-10", "2025-03-12"}}},
+		},
+		{
+			name:           "repeat calendar months",
+			period:         "M202503",
+			comparePeriods: []string{"RM3"},
+			lists: [][]testPeriod{{
+				{"2025-01-01", "2025-01-31"}, {"2025-02-01", "2025-02-28"}, {"2025-03-01", "2025-03-31"},
+			}},
+		},
+		{
+			name:           "repeat calendar quarters",
+			period:         "Q2025-2",
+			comparePeriods: []string{"RQ2"},
+			lists:          [][]testPeriod{{{"2025-01-01", "2025-03-31"}, {"2025-04-01", "2025-06-30"}}},
+		},
+		{
+			name:           "year over year keeps whole months",
+			period:         "M202502",
+			comparePeriods: []string{"RY3"},
+			lists: [][]testPeriod{{
+				{"2023-02-01", "2023-02-28"}, {"2024-02-01", "2024-02-29"}, {"2025-02-01", "2025-02-28"},
+			}},
+		},
+		{
+			name:           "year over year of a leap day",
+			period:         "T2024-02-29",
+			comparePeriods: []string{"RY2"},
+			lists:          [][]testPeriod{{{"2023-02-28", "2023-02-28"}, {"2024-02-29", "2024-02-29"}}},
+		},
+		{
+			name:           "repeat ignores other comparison periods",
+			period:         "M202503",
+			comparePeriods: []string{"RM2", "LM"},
+			lists:          [][]testPeriod{{{"2025-02-01", "2025-02-28"}, {"2025-03-01", "2025-03-31"}}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			lists, _, err := ParsePeriodLists(testDate(t, "2025-03-12"), test.period, test.comparePeriods...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lists) != len(test.lists) {
+				t.Fatalf("expected %d period lists, got %d", len(test.lists), len(lists))
+			}
+			for listIdx, list := range lists {
+				if len(list.Periods) != len(test.lists[listIdx]) {
+					t.Fatalf("list %d: expected %d periods, got %d", listIdx, len(test.lists[listIdx]), len(list.Periods))
+				}
+				for periodIdx, period := range list.Periods {
+					expected := test.lists[listIdx][periodIdx]
+					if !period.Start.Equal(testDate(t, expected.start)) || !period.End.Equal(testDate(t, expected.end)) {
+						t.Errorf("list %d period %d: expected %s to %s, got %s to %s", listIdx, periodIdx,
+							expected.start, expected.end, period.Start, period.End)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParsePeriodListsRepeatAmount(t *testing.T) {
+	lists, _, err := ParsePeriodLists(testDate(t, "2025-03-12"), "M202503", "RM36")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 1 || len(lists[0].Periods) != maxRepeatAmount {
+		t.Errorf("expected a list with %d periods", maxRepeatAmount)
+	}
+}
+
+func TestParsePeriodListsInvalid(t *testing.T) {
+	for _, test := range []struct {
+		period         string
+		comparePeriods []string
+	}{
+		{period: "M202513"},
+		{period: "M202503", comparePeriods: []string{"R0"}},
+		{period: "M202503", comparePeriods: []string{"R-1"}},
+		{period: "M202503", comparePeriods: []string{"R37"}},
+		{period: "M202503", comparePeriods: []string{"RY37"}},
+		{period: "M202503", comparePeriods: []string{"RX"}},
+		{period: "M202503", comparePeriods: []string{"R"}},
+		{period: "M202503", comparePeriods: []string{"LM", "x"}},
+		{period: "I202503", comparePeriods: []string{"RW2"}},
+		{period: "I202503", comparePeriods: []string{"R2"}},
+	} {
+		t.Run(test.period+" "+fmt.Sprint(test.comparePeriods), func(t *testing.T) {
+			if _, _, err := ParsePeriodLists(testDate(t, "2025-03-12"), test.period, test.comparePeriods...); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
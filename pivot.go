@@ -0,0 +1,140 @@
+package cloudcostexplorer
+
+import (
+	"cmp"
+	"slices"
+)
+
+// QueryPivot is a pivot table built from the query items, where the values of the last group become columns.
+// If there is only one group, the periods become the columns.
+type QueryPivot struct {
+	RowGroups    []QueryResultGroup
+	ColumnGroup  *QueryResultGroup // the group used as columns, or nil if the columns are the periods.
+	PeriodIdx    int               // the period of the values of the group columns.
+	Columns      []QueryPivotColumn
+	Rows         []*QueryPivotRow
+	ColumnTotals []float64
+	TotalValue   float64
+}
+
+// QueryPivotColumn is a pivot table column.
+type QueryPivotColumn struct {
+	Key    ItemKey
+	Period *QueryResultPeriod // only set if the columns are the periods.
+}
+
+// QueryPivotRow is a pivot table row, with one value for each column.
+type QueryPivotRow struct {
+	Keys       []ItemKey
+	Values     []float64
+	TotalValue float64
+}
+
+// buildPivot builds a pivot table from the result items. If there is more than one group, the values of the last group
+// become columns, using the values of the period with index "periodIdx". If there is only one group, the periods
+// become columns.
+// Rows and group columns are sorted by their total value, descending.
+func (r *QueryResult) buildPivot(periodIdx int) *QueryPivot {
+	if len(r.Groups) < 2 {
+		return r.periodPivot()
+	}
+	if periodIdx < 0 || periodIdx >= len(r.Periods) {
+		periodIdx = len(r.Periods) - 1
+	}
+
+	ret := &QueryPivot{
+		RowGroups:   r.Groups[:len(r.Groups)-1],
+		ColumnGroup: &r.Groups[len(r.Groups)-1],
+		PeriodIdx:   periodIdx,
+	}
+
+	columnIdx := map[string]int{}
+	var columnTotals []float64
+	for _, item := range r.Items {
+		key := item.Keys[len(item.Keys)-1]
+		if _, ok := columnIdx[key.ID]; !ok {
+			columnIdx[key.ID] = len(ret.Columns)
+			ret.Columns = append(ret.Columns, QueryPivotColumn{
+				Key: key,
+			})
+			columnTotals = append(columnTotals, 0)
+		}
+		columnTotals[columnIdx[key.ID]] += item.Values[periodIdx]
+	}
+
+	// sort columns by total value
+	columnOrder := make([]int, len(ret.Columns))
+	for i := range columnOrder {
+		columnOrder[i] = i
+	}
+	slices.SortStableFunc(columnOrder, func(a, b int) int {
+		return cmp.Compare(columnTotals[b], columnTotals[a])
+	})
+	columns := make([]QueryPivotColumn, len(ret.Columns))
+	ret.ColumnTotals = make([]float64, len(ret.Columns))
+	for newIdx, oldIdx := range columnOrder {
+		columns[newIdx] = ret.Columns[oldIdx]
+		ret.ColumnTotals[newIdx] = columnTotals[oldIdx]
+		columnIdx[ret.Columns[oldIdx].Key.ID] = newIdx
+	}
+	ret.Columns = columns
+
+	rows := map[string]*QueryPivotRow{}
+	for _, item := range r.Items {
+		rowKeys := item.Keys[:len(item.Keys)-1]
+		rowHash := DefaultItemKeysHash(rowKeys)
+		row, ok := rows[rowHash]
+		if !ok {
+			row = &QueryPivotRow{
+				Keys:   rowKeys,
+				Values: make([]float64, len(ret.Columns)),
+			}
+			rows[rowHash] = row
+			ret.Rows = append(ret.Rows, row)
+		}
+		value := item.Values[periodIdx]
+		row.Values[columnIdx[item.Keys[len(item.Keys)-1].ID]] += value
+		row.TotalValue += value
+		ret.TotalValue += value
+	}
+
+	ret.sortRows()
+	return ret
+}
+
+// periodPivot builds a pivot table where the columns are the periods.
+func (r *QueryResult) periodPivot() *QueryPivot {
+	ret := &QueryPivot{
+		RowGroups: r.Groups,
+	}
+	for periodIdx, period := range r.Periods {
+		ret.Columns = append(ret.Columns, QueryPivotColumn{
+			Key: ItemKey{
+				ID:    period.StringFilter(),
+				Value: period.String(),
+			},
+			Period: &r.Periods[periodIdx],
+		})
+		ret.ColumnTotals = append(ret.ColumnTotals, period.TotalValue)
+		ret.TotalValue += period.TotalValue
+	}
+	for _, item := range r.Items {
+		row := &QueryPivotRow{
+			Keys:   item.Keys,
+			Values: slices.Clone(item.Values),
+		}
+		for _, value := range item.Values {
+			row.TotalValue += value
+		}
+		ret.Rows = append(ret.Rows, row)
+	}
+
+	ret.sortRows()
+	return ret
+}
+
+func (p *QueryPivot) sortRows() {
+	slices.SortStableFunc(p.Rows, func(a, b *QueryPivotRow) int {
+		return cmp.Compare(b.TotalValue, a.TotalValue)
+	})
+}
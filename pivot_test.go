@@ -0,0 +1,137 @@
+package cloudcostexplorer
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// testPivotQuery queries February and March 2025 from a [testCloud] with a pivot table.
+func testPivotQuery(t *testing.T, periodIdx int, groups ...QueryGroup) *QueryPivot {
+	t.Helper()
+	cloud := &testCloud{
+		rows: []testCloudRow{
+			{keys: map[string]string{"SERVICE": "compute", "REGION": "us"}, value: 1},
+			{keys: map[string]string{"SERVICE": "compute", "REGION": "eu"}, value: 2},
+			{keys: map[string]string{"SERVICE": "storage", "REGION": "us"}, value: 4},
+		},
+	}
+	periodLists, _, err := ParsePeriodLists(testDate(t, "2025-04-10"), "M202503", "RM2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := QueryHandler(context.Background(), cloud,
+		WithQueryHandlerGroups(groups...),
+		WithQueryHandlerPeriodLists(periodLists...),
+		WithQueryHandlerPivot(periodIdx),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pivot == nil {
+		t.Fatal("expected a pivot table")
+	}
+	return result.Pivot
+}
+
+// testPivotRow is the expected row of a pivot table.
+type testPivotRow struct {
+	key    string
+	values []float64
+	total  float64
+}
+
+func checkPivot(t *testing.T, pivot *QueryPivot, columns []string, columnTotals []float64, rows []testPivotRow, total float64) {
+	t.Helper()
+	var pivotColumns []string
+	for _, column := range pivot.Columns {
+		pivotColumns = append(pivotColumns, column.Key.ID)
+	}
+	if !slices.Equal(pivotColumns, columns) {
+		t.Errorf("expected columns %v, got %v", columns, pivotColumns)
+	}
+	if !slices.Equal(pivot.ColumnTotals, columnTotals) {
+		t.Errorf("expected column totals %v, got %v", columnTotals, pivot.ColumnTotals)
+	}
+	if len(pivot.Rows) != len(rows) {
+		t.Fatalf("expected %d rows, got %d", len(rows), len(pivot.Rows))
+	}
+	for rowIdx, row := range pivot.Rows {
+		if row.Keys[0].ID != rows[rowIdx].key {
+			t.Errorf("row %d: expected key '%s', got '%s'", rowIdx, rows[rowIdx].key, row.Keys[0].ID)
+		}
+		if !slices.Equal(row.Values, rows[rowIdx].values) {
+			t.Errorf("row %d: expected values %v, got %v", rowIdx, rows[rowIdx].values, row.Values)
+		}
+		if row.TotalValue != rows[rowIdx].total {
+			t.Errorf("row %d: expected total %v, got %v", rowIdx, rows[rowIdx].total, row.TotalValue)
+		}
+	}
+	if pivot.TotalValue != total {
+		t.Errorf("expected total %v, got %v", total, pivot.TotalValue)
+	}
+}
+
+func TestQueryPivotGroupColumns(t *testing.T) {
+	// February 2025 has 28 days. Rows and columns are sorted by total, and storage has no "eu" value.
+	pivot := testPivotQuery(t, 0, QueryGroup{ID: "SERVICE"}, QueryGroup{ID: "REGION"})
+
+	if pivot.ColumnGroup == nil || pivot.ColumnGroup.ID != "REGION" {
+		t.Error("expected the last group as columns")
+	}
+	if len(pivot.RowGroups) != 1 || pivot.RowGroups[0].ID != "SERVICE" {
+		t.Error("expected the first group as rows")
+	}
+	if pivot.PeriodIdx != 0 {
+		t.Errorf("expected period 0, got %d", pivot.PeriodIdx)
+	}
+	checkPivot(t, pivot,
+		[]string{"us", "eu"},
+		[]float64{140, 56},
+		[]testPivotRow{
+			{key: "storage", values: []float64{112, 0}, total: 112},
+			{key: "compute", values: []float64{28, 56}, total: 84},
+		},
+		196)
+}
+
+func TestQueryPivotPeriodOutOfRange(t *testing.T) {
+	// uses the last period, March 2025, with 31 days.
+	pivot := testPivotQuery(t, 5, QueryGroup{ID: "SERVICE"}, QueryGroup{ID: "REGION"})
+
+	if pivot.PeriodIdx != 1 {
+		t.Errorf("expected period 1, got %d", pivot.PeriodIdx)
+	}
+	checkPivot(t, pivot,
+		[]string{"us", "eu"},
+		[]float64{155, 62},
+		[]testPivotRow{
+			{key: "storage", values: []float64{124, 0}, total: 124},
+			{key: "compute", values: []float64{31, 62}, total: 93},
+		},
+		217)
+}
+
+func TestQueryPivotPeriodColumns(t *testing.T) {
+	pivot := testPivotQuery(t, 0, QueryGroup{ID: "SERVICE"})
+
+	if pivot.ColumnGroup != nil {
+		t.Error("expected the periods as columns")
+	}
+	for columnIdx, column := range pivot.Columns {
+		if column.Period == nil || column.Period.TotalValue != pivot.ColumnTotals[columnIdx] {
+			t.Errorf("column %d: expected the period with its total", columnIdx)
+		}
+	}
+	if len(pivot.Columns) != 2 {
+		t.Fatalf("expected 2 columns, got %d", len(pivot.Columns))
+	}
+	checkPivot(t, pivot,
+		[]string{pivot.Columns[0].Period.StringFilter(), pivot.Columns[1].Period.StringFilter()},
+		[]float64{196, 217},
+		[]testPivotRow{
+			{key: "storage", values: []float64{112, 124}, total: 236},
+			{key: "compute", values: []float64{84, 93}, total: 177},
+		},
+		413)
+}
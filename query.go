@@ -4,23 +4,28 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"slices"
 
 	"github.com/invzhi/timex"
 )
 
 type CloudQueryItem struct {
-	Date  timex.Date
-	Keys  []ItemKey
-	Value float64
+	Date    timex.Date
+	Keys    []ItemKey
+	Value   float64 // the net cost, including credits.
+	Credits float64 // the credits included in Value, if the cloud supports it. Usually a negative value.
 }
 
 type QueryResult struct {
 	Items               []*Item
+	Tree                []*ItemTreeNode // only set if requested with [WithQueryHandlerTree].
 	TotalValue          float64
 	Groups              []QueryResultGroup
 	PeriodsSameDuration bool
 	Periods             []QueryResultPeriod
 	ExtraOutput         QueryExtraOutput
+	QueryInfo           []QueryInfo // debug information of the queries executed by the cloud.
+	Pivot               *QueryPivot // only set if requested with [WithQueryHandlerPivot].
 }
 
 // QueryFilter is the ID and value of a filter.
@@ -37,7 +42,9 @@ type QueryGroup struct {
 
 // QueryPeriodList is a list of periods to query.
 type QueryPeriodList struct {
-	Periods []QueryPeriod
+	Periods      []QueryPeriod
+	Description  string // optional description of the periods, like "14 days".
+	InvoiceMonth bool   // whether the periods select invoice months instead of usage dates. See [WithQueryInvoiceMonth].
 }
 
 func (l QueryPeriodList) Range() (bool, timex.Date, timex.Date) {
@@ -56,6 +63,20 @@ func (l QueryPeriodList) Range() (bool, timex.Date, timex.Date) {
 	return true, *start, *end
 }
 
+// IsContiguous returns whether the periods cover a single range of days, without days between them which are not in
+// any period.
+func (l QueryPeriodList) IsContiguous() bool {
+	periods := slices.SortedFunc(slices.Values(l.Periods), func(a, b QueryPeriod) int {
+		return a.Start.Sub(b.Start)
+	})
+	for i := 1; i < len(periods); i++ {
+		if periods[i].Start.After(periods[i-1].End.AddDays(1)) {
+			return false
+		}
+	}
+	return true
+}
+
 // QueryPeriod is a single period to query.
 type QueryPeriod struct {
 	ID         string // optional ID that can be used by the caller to identify the returned period. Not used by the library.
@@ -83,10 +104,36 @@ func (q QueryResultGroup) Title(isMenu bool) string {
 	return ret
 }
 
+// PeriodsMultipleYears returns whether the result periods are not all on the same year.
+func (r *QueryResult) PeriodsMultipleYears() bool {
+	for _, period := range r.Periods {
+		if period.Start.Year() != r.Periods[0].Start.Year() || period.End.Year() != r.Periods[0].Start.Year() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCredits returns whether any period contains credits.
+func (r *QueryResult) HasCredits() bool {
+	for _, period := range r.Periods {
+		if period.TotalCredits != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // QueryResultPeriod is a period that was used to query the results.
 type QueryResultPeriod struct {
 	QueryPeriod
-	TotalValue float64
+	TotalValue   float64
+	TotalCredits float64
+}
+
+// TotalGrossValue returns the total value of the period before credits were applied.
+func (q QueryResultPeriod) TotalGrossValue() float64 {
+	return q.TotalValue - q.TotalCredits
 }
 
 // String returns a short string representation of the period.
@@ -108,13 +155,30 @@ func (q QueryPeriod) StringFilter() string {
 
 // StringWithDuration may append the duration to the String result.
 func (q QueryPeriod) StringWithDuration(showDuration bool) string {
+	return q.Format(false, showDuration)
+}
+
+// Format returns a short string representation of the period, optionally with the year and the duration.
+func (q QueryPeriod) Format(showYear bool, showDuration bool) string {
 	s := q.String()
+	if showYear {
+		if q.Start.Year() == q.End.Year() {
+			s += fmt.Sprintf(" %d", q.End.Year())
+		} else {
+			s = fmt.Sprintf("%s %d-%s %d", FormatShortDate(q.Start), q.Start.Year(), FormatShortDate(q.End), q.End.Year())
+		}
+	}
 	if showDuration {
-		s += fmt.Sprintf(" (%d days)", q.End.Sub(q.Start)+1)
+		s += fmt.Sprintf(" (%d days)", q.Days())
 	}
 	return s
 }
 
+// Days returns the number of days of the period, including the start and end days.
+func (q QueryPeriod) Days() int {
+	return q.End.Sub(q.Start) + 1
+}
+
 func NewQueryPeriod(start, end timex.Date) QueryPeriod {
 	return QueryPeriod{
 		Start: start,
@@ -133,6 +197,32 @@ type QueryExtraOutput interface {
 	ExtraOutputs() iter.Seq2[ValueOutput, error]
 }
 
+// QueryInfo is debug information about a query executed by the cloud, to check the exact query that ran.
+type QueryInfo struct {
+	Title      string           // like "BigQuery" or "GetCostAndUsage".
+	Query      string           // the query as executed, like the SQL or the API input JSON.
+	IsSQL      bool             // whether the query is SQL which can be run in a SQL console.
+	CopyQuery  string           // the query ready to be run as is, like the SQL with the parameters inlined. Default is Query.
+	Parameters []QueryInfoValue // bound query parameters.
+	Stats      []QueryInfoValue // execution statistics, like the latency and page count.
+}
+
+// QueryInfoValue is a name and value of [QueryInfo].
+type QueryInfoValue struct {
+	Name  string
+	Value string
+}
+
+// ConfirmationRequiredError is returned by [Cloud.Query] when the query must be explicitly confirmed before running,
+// like one which is estimated to be expensive. It can be run again using [WithQueryConfirmed].
+type ConfirmationRequiredError struct {
+	Message string
+}
+
+func (e *ConfirmationRequiredError) Error() string {
+	return e.Message
+}
+
 type QueryOption func(options *QueryOptions)
 
 // ParseQueryOptions parses the default query options.
@@ -166,6 +256,15 @@ func WithQueryGroupByDate(groupByDate bool) QueryOption {
 	}
 }
 
+// WithQueryInvoiceMonth sets whether to select costs by invoice (billing) month instead of usage date.
+// In this mode, all costs invoiced in the months from the start date to the end date are returned, including late
+// usage and adjustments, and the item dates are the first day of the invoice month.
+func WithQueryInvoiceMonth(invoiceMonth bool) QueryOption {
+	return func(options *QueryOptions) {
+		options.InvoiceMonth = invoiceMonth
+	}
+}
+
 // WithQueryGroups sets the grouping to use for the query.
 func WithQueryGroups(groups ...QueryGroup) QueryOption {
 	return func(options *QueryOptions) {
@@ -188,10 +287,28 @@ func WithQueryExtraData(extraDataCallback func(data QueryExtraData)) QueryOption
 	}
 }
 
+// WithQueryInfo sets a callback to receive debug information about each query executed by the cloud.
+func WithQueryInfo(queryInfoCallback func(info QueryInfo)) QueryOption {
+	return func(options *QueryOptions) {
+		options.QueryInfoCallback = queryInfoCallback
+	}
+}
+
+// WithQueryConfirmed sets whether the query was confirmed by the user, so it runs even if it would return a
+// [ConfirmationRequiredError].
+func WithQueryConfirmed(confirmed bool) QueryOption {
+	return func(options *QueryOptions) {
+		options.Confirmed = confirmed
+	}
+}
+
 type QueryOptions struct {
 	Start, End        timex.Date
 	GroupByDate       bool
+	InvoiceMonth      bool
 	Groups            []QueryGroup
 	Filters           []QueryFilter
 	ExtraDataCallback func(data QueryExtraData)
+	QueryInfoCallback func(info QueryInfo)
+	Confirmed         bool
 }
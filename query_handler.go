@@ -52,26 +52,28 @@ func QueryHandler(ctx context.Context, cloud Cloud, options ...QueryHandlerOptio
 
 	var extraData []QueryExtraData
 
-	periodStart := 0
-	for _, periodList := range optns.periodLists {
-		if len(periodList.Periods) == 0 {
-			continue
-		}
-
-		ok, start, end := periodList.Range()
+	// queryPeriods queries a range of periods, adding the values to the periods starting at index "periodStart".
+	// Items outside all periods are ignored.
+	queryPeriods := func(periods []QueryPeriod, periodStart int, invoiceMonth bool) error {
+		ok, start, end := QueryPeriodList{Periods: periods}.Range()
 		if !ok {
-			continue
+			return nil
 		}
 
-		isSinglePeriod := len(periodList.Periods) == 1
+		isSinglePeriod := len(periods) == 1
 
 		qopts := []QueryOption{
 			WithQueryDates(start, end),
+			WithQueryInvoiceMonth(invoiceMonth),
 			WithQueryGroups(optns.groups...),
 			WithQueryFilters(optns.filters...),
 			WithQueryExtraData(func(data QueryExtraData) {
 				extraData = append(extraData, data)
 			}),
+			WithQueryConfirmed(optns.confirmed),
+			WithQueryInfo(func(info QueryInfo) {
+				ret.QueryInfo = append(ret.QueryInfo, info)
+			}),
 		}
 
 		if !isSinglePeriod {
@@ -80,32 +82,57 @@ func QueryHandler(ctx context.Context, cloud Cloud, options ...QueryHandlerOptio
 
 		for item, err := range cloud.Query(ctx, qopts...) {
 			if err != nil {
-				return nil, err
+				return err
 			}
 
 			if optns.filterKeys != nil && !optns.filterKeys(item.Keys) {
 				continue
 			}
 
+			var periodMatches []int
+			for periodIdx, period := range periods {
+				if isSinglePeriod || DateBetweenDates(item.Date, period.Start, period.End) {
+					periodMatches = append(periodMatches, periodStart+periodIdx)
+				}
+			}
+
+			if len(periodMatches) != 1 && optns.onPeriodMatchError != nil {
+				err := optns.onPeriodMatchError(item, len(periodMatches))
+				if err != nil {
+					return err
+				}
+			}
+			if len(periodMatches) == 0 {
+				continue
+			}
+
 			itemHash := optns.itemKeysHash(item.Keys)
 			if _, ok := items[itemHash]; !ok {
 				items[itemHash] = NewItem(item.Keys, len(ret.Periods))
 			}
 			ret.TotalValue += item.Value
 
-			periodMatches := 0
-			for periodIdx, period := range periodList.Periods {
-				if !isSinglePeriod && !DateBetweenDates(item.Date, period.Start, period.End) {
-					continue
-				}
-				items[itemHash].Values[periodStart+periodIdx] += item.Value
-				ret.Periods[periodStart+periodIdx].TotalValue += item.Value
-				periodMatches++
+			for _, periodIdx := range periodMatches {
+				items[itemHash].Values[periodIdx] += item.Value
+				items[itemHash].Credits[periodIdx] += item.Credits
+				ret.Periods[periodIdx].TotalValue += item.Value
+				ret.Periods[periodIdx].TotalCredits += item.Credits
 			}
+		}
+		return nil
+	}
 
-			if periodMatches != 1 && optns.onPeriodMatchError != nil {
-				err := optns.onPeriodMatchError(item, periodStart)
-				if err != nil {
+	periodStart := 0
+	for _, periodList := range optns.periodLists {
+		if periodList.IsContiguous() {
+			if err := queryPeriods(periodList.Periods, periodStart, periodList.InvoiceMonth); err != nil {
+				return nil, err
+			}
+		} else {
+			// periods with days between them, like year over year ones, are queried separately so the days between
+			// them are not queried.
+			for periodIdx, period := range periodList.Periods {
+				if err := queryPeriods([]QueryPeriod{period}, periodStart+periodIdx, periodList.InvoiceMonth); err != nil {
 					return nil, err
 				}
 			}
@@ -116,6 +143,12 @@ func QueryHandler(ctx context.Context, cloud Cloud, options ...QueryHandlerOptio
 
 	ret.Items = slices.Collect(maps.Values(items))
 	ret.ExtraOutput = cloud.QueryExtraOutput(ctx, extraData)
+	if optns.tree {
+		ret.Tree = NewItemTree(ret.Items, len(ret.Periods))
+	}
+	if optns.pivot {
+		ret.Pivot = ret.buildPivot(optns.pivotPeriodIdx)
+	}
 
 	return &ret, nil
 }
@@ -189,6 +222,30 @@ func WithQueryHandlerOnPeriodMatchError(onPeriodMatchError func(item CloudQueryI
 	}
 }
 
+// WithQueryHandlerConfirmed sets whether the queries were confirmed by the user. See [WithQueryConfirmed].
+func WithQueryHandlerConfirmed(confirmed bool) QueryHandlerOption {
+	return func(options *queryHandlerOptions) {
+		options.confirmed = confirmed
+	}
+}
+
+// WithQueryHandlerPivot sets [QueryResult.Pivot] to a pivot table of the result. If there is more than one group,
+// the values of the last group become columns, using the values of the period with index "periodIdx", or the last
+// period if out of range. If there is only one group, the periods become columns.
+func WithQueryHandlerPivot(periodIdx int) QueryHandlerOption {
+	return func(options *queryHandlerOptions) {
+		options.pivot = true
+		options.pivotPeriodIdx = periodIdx
+	}
+}
+
+// WithQueryHandlerTree sets [QueryResult.Tree] to the items grouped hierarchically by each group, with subtotals.
+func WithQueryHandlerTree() QueryHandlerOption {
+	return func(options *queryHandlerOptions) {
+		options.tree = true
+	}
+}
+
 type queryHandlerOptions struct {
 	periodLists        []QueryPeriodList
 	groups             []QueryGroup
@@ -196,4 +253,8 @@ type queryHandlerOptions struct {
 	filterKeys         func(keys []ItemKey) bool
 	itemKeysHash       func(keys []ItemKey) string
 	onPeriodMatchError func(item CloudQueryItem, matchCount int) error
+	confirmed          bool
+	tree               bool
+	pivot              bool
+	pivotPeriodIdx     int
 }
@@ -0,0 +1,192 @@
+package cloudcostexplorer
+
+import (
+	"context"
+	"iter"
+	"testing"
+
+	"github.com/invzhi/timex"
+)
+
+// testCloud is a [Cloud] where each row costs its value on every day.
+type testCloud struct {
+	rows    []testCloudRow
+	queries []QueryOptions // the options of each query.
+}
+
+// testCloudRow is a row of [testCloud], with the key of each group ID.
+type testCloudRow struct {
+	keys  map[string]string
+	value float64
+}
+
+func (c *testCloud) DaysDelay() int {
+	return 0
+}
+
+func (c *testCloud) MaxGroupBy() int {
+	return 3
+}
+
+func (c *testCloud) Parameters() Parameters {
+	return Parameters{
+		{ID: "SERVICE", Name: "Service", IsGroup: true, IsFilter: true},
+		{ID: "REGION", Name: "Region", IsGroup: true, IsFilter: true},
+		{ID: "ACCOUNT", Name: "Account", IsGroup: true, IsFilter: true},
+	}
+}
+
+func (c *testCloud) ParameterTitle(id string, defaultValue string) string {
+	return defaultValue
+}
+
+func (c *testCloud) Query(ctx context.Context, options ...QueryOption) iter.Seq2[CloudQueryItem, error] {
+	return func(yield func(CloudQueryItem, error) bool) {
+		optns, err := ParseQueryOptions(options...)
+		if err != nil {
+			yield(CloudQueryItem{}, err)
+			return
+		}
+		c.queries = append(c.queries, optns)
+
+		for _, row := range c.rows {
+			var keys []ItemKey
+			for _, group := range optns.Groups {
+				keys = append(keys, ItemKey{ID: row.keys[group.ID], Value: row.keys[group.ID]})
+			}
+			if !optns.GroupByDate {
+				days := float64(optns.End.Sub(optns.Start) + 1)
+				if !yield(CloudQueryItem{Keys: keys, Value: row.value * days}, nil) {
+					return
+				}
+				continue
+			}
+			for date := optns.Start; !date.After(optns.End); date = date.AddDays(1) {
+				if !yield(CloudQueryItem{Date: date, Keys: keys, Value: row.value}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *testCloud) QueryExtraOutput(ctx context.Context, extraData []QueryExtraData) QueryExtraOutput {
+	return nil
+}
+
+func TestQueryHandlerYearOverYear(t *testing.T) {
+	cloud := &testCloud{
+		rows: []testCloudRow{
+			{keys: map[string]string{"SERVICE": "compute"}, value: 1},
+			{keys: map[string]string{"SERVICE": "storage"}, value: 2},
+		},
+	}
+
+	periodLists, _, err := ParsePeriodLists(timex.MustNewDate(2025, 6, 1), "M202502", "RY3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var matchErrors int
+	result, err := QueryHandler(context.Background(), cloud,
+		WithQueryHandlerGroups(QueryGroup{ID: "SERVICE"}),
+		WithQueryHandlerPeriodLists(periodLists...),
+		WithQueryHandlerOnPeriodMatchError(func(item CloudQueryItem, matchCount int) error {
+			matchErrors++
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// February has 28 days in 2023 and 2025, and 29 in 2024.
+	expectedDays := []int{28, 29, 28}
+	if len(result.Periods) != len(expectedDays) {
+		t.Fatalf("expected %d periods, got %d", len(expectedDays), len(result.Periods))
+	}
+	var periodsTotal float64
+	for periodIdx, days := range expectedDays {
+		if expected := float64(days * 3); result.Periods[periodIdx].TotalValue != expected {
+			t.Errorf("period %d: expected total %v, got %v", periodIdx, expected, result.Periods[periodIdx].TotalValue)
+		}
+		periodsTotal += result.Periods[periodIdx].TotalValue
+	}
+	if result.TotalValue != periodsTotal {
+		t.Errorf("expected total %v equal to the sum of the periods, got %v", periodsTotal, result.TotalValue)
+	}
+	if matchErrors != 0 {
+		t.Errorf("expected no period match errors, got %d", matchErrors)
+	}
+
+	for _, query := range cloud.queries {
+		if days := query.End.Sub(query.Start) + 1; days > 29 {
+			t.Errorf("expected each query to be a single period, got %s to %s", query.Start, query.End)
+		}
+	}
+}
+
+func TestQueryHandlerContiguousPeriods(t *testing.T) {
+	cloud := &testCloud{
+		rows: []testCloudRow{
+			{keys: map[string]string{"SERVICE": "compute"}, value: 1},
+		},
+	}
+
+	periodLists, _, err := ParsePeriodLists(timex.MustNewDate(2025, 6, 1), "M202503", "RM3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := QueryHandler(context.Background(), cloud,
+		WithQueryHandlerGroups(QueryGroup{ID: "SERVICE"}),
+		WithQueryHandlerPeriodLists(periodLists...),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cloud.queries) != 1 || !cloud.queries[0].GroupByDate {
+		t.Fatalf("expected a single query grouped by date, got %d queries", len(cloud.queries))
+	}
+	// January, February and March 2025.
+	if result.TotalValue != 31+28+31 {
+		t.Errorf("expected total 90, got %v", result.TotalValue)
+	}
+}
+
+func TestQueryPeriodListIsContiguous(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		periods  []QueryPeriod
+		expected bool
+	}{
+		{
+			name:     "single period",
+			periods:  []QueryPeriod{NewQueryPeriod(timex.MustNewDate(2025, 1, 1), timex.MustNewDate(2025, 1, 31))},
+			expected: true,
+		},
+		{
+			name: "consecutive periods out of order",
+			periods: []QueryPeriod{
+				NewQueryPeriod(timex.MustNewDate(2025, 2, 1), timex.MustNewDate(2025, 2, 28)),
+				NewQueryPeriod(timex.MustNewDate(2025, 1, 1), timex.MustNewDate(2025, 1, 31)),
+			},
+			expected: true,
+		},
+		{
+			name: "days between periods",
+			periods: []QueryPeriod{
+				NewQueryPeriod(timex.MustNewDate(2024, 1, 1), timex.MustNewDate(2024, 1, 31)),
+				NewQueryPeriod(timex.MustNewDate(2025, 1, 1), timex.MustNewDate(2025, 1, 31)),
+			},
+			expected: false,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := (QueryPeriodList{Periods: test.periods}).IsContiguous(); got != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, got)
+			}
+		})
+	}
+}
@@ -17,11 +17,17 @@ func FromCivilDate(dt civil.Date) timex.Date {
 	return timex.MustNewDate(dt.Year, int(dt.Month), dt.Day)
 }
 
-// TimeStartEnd converts 2 [timex.Date] values to 2 [time.Time] values, with the time part set to 00:00:00 and 23:59:59
-// respectively.
+// TimeStartEnd converts 2 [timex.Date] values to 2 [time.Time] values in UTC, with the time part set to 00:00:00 and
+// 23:59:59 respectively.
 func TimeStartEnd(start, end timex.Date) (time.Time, time.Time) {
-	return time.Date(start.Year(), time.Month(start.Month()), start.Day(), 0, 0, 0, 0, time.UTC),
-		time.Date(end.Year(), time.Month(end.Month()), end.Day(), 23, 59, 59, 999999, time.UTC)
+	return TimeStartEndInLocation(start, end, time.UTC)
+}
+
+// TimeStartEndInLocation converts 2 [timex.Date] values to 2 [time.Time] values in the passed location, with the time
+// part set to 00:00:00 and 23:59:59 respectively.
+func TimeStartEndInLocation(start, end timex.Date, location *time.Location) (time.Time, time.Time) {
+	return time.Date(start.Year(), time.Month(start.Month()), start.Day(), 0, 0, 0, 0, location),
+		time.Date(end.Year(), time.Month(end.Month()), end.Day(), 23, 59, 59, 999999, location)
 }
 
 // DateBetweenDates returns whether the passed date is between start and end.
@@ -45,12 +51,123 @@ func GenerateQueryPeriods(start, end timex.Date, amount int) []QueryPeriod {
 	return periods
 }
 
+// CalendarUnit is a calendar unit used to generate calendar-aligned periods.
+type CalendarUnit int
+
+const (
+	CalendarUnitWeek    CalendarUnit = iota // weeks starting on Monday.
+	CalendarUnitMonth                       // calendar months.
+	CalendarUnitQuarter                     // calendar quarters.
+)
+
+// Start returns the first day of the unit containing the passed date.
+func (u CalendarUnit) Start(date timex.Date) timex.Date {
+	switch u {
+	case CalendarUnitWeek:
+		return StartOfWeek(date)
+	case CalendarUnitQuarter:
+		return StartOfQuarter(date)
+	default:
+		return StartOfMonth(date)
+	}
+}
+
+// Add adds "amount" units to a date which is the first day of the unit.
+func (u CalendarUnit) Add(start timex.Date, amount int) timex.Date {
+	switch u {
+	case CalendarUnitWeek:
+		return start.AddDays(7 * amount)
+	case CalendarUnitQuarter:
+		return start.Add(0, 3*amount, 0)
+	default:
+		return start.Add(0, amount, 0)
+	}
+}
+
+// GenerateCalendarQueryPeriods generates "amount" calendar-aligned periods of the passed unit, one before the other,
+// where the last one is the unit containing "end". The last period ends on "end", so it may not be complete.
+func GenerateCalendarQueryPeriods(end timex.Date, unit CalendarUnit, amount int) []QueryPeriod {
+	lastStart := unit.Start(end)
+	var periods []QueryPeriod
+	for i := amount - 1; i >= 0; i-- {
+		pstart := unit.Add(lastStart, -i)
+		pend := end
+		if i > 0 {
+			pend = unit.Add(pstart, 1).AddDays(-1)
+		}
+		periods = append(periods, QueryPeriod{
+			Start: pstart,
+			End:   pend,
+		})
+	}
+	return periods
+}
+
+// GenerateYearOverYearQueryPeriods generates "amount" periods with the same dates as the passed range, on the
+// previous years. February 29 is changed to February 28 on non-leap years. If the range is made of whole calendar
+// months, the periods are also whole calendar months, so a February always ends on its last day.
+func GenerateYearOverYearQueryPeriods(start, end timex.Date, amount int) []QueryPeriod {
+	wholeMonths := start.Equal(StartOfMonth(start)) && end.Equal(EndingOfMonth(StartOfMonth(end)))
+
+	var periods []QueryPeriod
+	for i := amount - 1; i >= 0; i-- {
+		pstart, pend := addYears(start, -i), addYears(end, -i)
+		if wholeMonths {
+			pend = EndingOfMonth(StartOfMonth(pend))
+		}
+		periods = append(periods, QueryPeriod{
+			Start: pstart,
+			End:   pend,
+		})
+	}
+	return periods
+}
+
+// addYears adds years to a date, keeping it in the same month.
+func addYears(date timex.Date, years int) timex.Date {
+	monthStart := timex.MustNewDate(date.Year()+years, date.Month(), 1)
+	if monthEnd := EndingOfMonth(monthStart); date.Day() > monthEnd.Day() {
+		return monthEnd
+	}
+	return monthStart.AddDays(date.Day() - 1)
+}
+
 // EndingOfMonth returns the last day of the month/year of the passed date.
 func EndingOfMonth(date timex.Date) timex.Date {
 	// return date.Add(0, 1, -date.Day()+1)
 	return date.Add(0, 1, -1)
 }
 
+// StartOfMonth returns the first day of the month of the passed date.
+func StartOfMonth(date timex.Date) timex.Date {
+	return date.AddDays(-date.Day() + 1)
+}
+
+// StartOfQuarter returns the first day of the quarter of the passed date.
+func StartOfQuarter(date timex.Date) timex.Date {
+	return timex.MustNewDate(date.Year(), (date.Quarter()-1)*3+1, 1)
+}
+
+// StartOfYear returns the first day of the year of the passed date.
+func StartOfYear(date timex.Date) timex.Date {
+	return timex.MustNewDate(date.Year(), 1, 1)
+}
+
+// StartOfWeek returns the Monday of the week of the passed date.
+func StartOfWeek(date timex.Date) timex.Date {
+	return date.AddDays(-((int(date.Weekday()) + 6) % 7))
+}
+
+// StartOfISOWeek returns the Monday of the passed ISO 8601 year and week.
+func StartOfISOWeek(year, week int) (timex.Date, error) {
+	// week 1 is the week containing January 4th.
+	ret := StartOfWeek(timex.MustNewDate(year, 1, 4)).AddDays((week - 1) * 7)
+	if wy, ww := ret.ISOWeek(); wy != year || ww != week {
+		return timex.Date{}, fmt.Errorf("invalid ISO week %d-%d", year, week)
+	}
+	return ret, nil
+}
+
 // FormatShortDate formats a date like "Feb 15".
 func FormatShortDate(dt timex.Date) string {
 	return fmt.Sprintf("%s %d", shortMonthNames[dt.Month()-1], dt.Day())
@@ -2,28 +2,41 @@ package cloudcostexplorer
 
 import (
 	"iter"
-	"maps"
 	"net/url"
+	"slices"
 )
 
-// URLQuery is a URL query builder.
+// URLQuery is a URL query builder. A key may contain multiple values.
 type URLQuery struct {
 	path   string
-	params map[string]string
+	params map[string][]string
 }
 
 func NewURLQuery(path string) *URLQuery {
 	return &URLQuery{
 		path:   path,
-		params: make(map[string]string),
+		params: make(map[string][]string),
 	}
 }
 
+// NewURLQueryFromValues creates a query with a copy of the passed values, usually from the request.
+func NewURLQueryFromValues(path string, values url.Values) *URLQuery {
+	ret := NewURLQuery(path)
+	for k, v := range values {
+		ret.params[k] = slices.Clone(v)
+	}
+	return ret
+}
+
 // Clone clones the query to a new instance.
 func (q *URLQuery) Clone() *URLQuery {
+	params := make(map[string][]string, len(q.params))
+	for k, v := range q.params {
+		params[k] = slices.Clone(v)
+	}
 	return &URLQuery{
 		path:   q.path,
-		params: maps.Clone(q.params),
+		params: params,
 	}
 }
 
@@ -36,8 +49,30 @@ func (q *URLQuery) SetPath(path string) *URLQuery {
 	return q
 }
 
+// Set sets the value of the key, replacing any existing values.
 func (q *URLQuery) Set(key, value string) *URLQuery {
-	q.params[key] = value
+	q.params[key] = []string{value}
+	return q
+}
+
+// Add adds a value to the key, if it is not already set.
+func (q *URLQuery) Add(key, value string) *URLQuery {
+	if !slices.Contains(q.params[key], value) {
+		q.params[key] = append(q.params[key], value)
+	}
+	return q
+}
+
+// RemoveValue removes a single value from the key, keeping the other values.
+func (q *URLQuery) RemoveValue(key, value string) *URLQuery {
+	values := slices.DeleteFunc(q.params[key], func(v string) bool {
+		return v == value
+	})
+	if len(values) == 0 {
+		delete(q.params, key)
+	} else {
+		q.params[key] = values
+	}
 	return q
 }
 
@@ -50,7 +85,7 @@ func (q *URLQuery) SetFromQuery(query url.Values, keys ...string) *URLQuery {
 
 func (q *URLQuery) Copy(keyFrom, keyTo string) *URLQuery {
 	if _, ok := q.params[keyFrom]; ok {
-		q.params[keyTo] = q.params[keyFrom]
+		q.params[keyTo] = slices.Clone(q.params[keyFrom])
 	}
 	return q
 }
@@ -74,8 +109,17 @@ func (q *URLQuery) Swap(key1, key2 string) *URLQuery {
 	return q
 }
 
+// Get returns the first value of the key.
 func (q *URLQuery) Get(key string) string {
-	return q.params[key]
+	if values := q.params[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Values returns all the values of the key.
+func (q *URLQuery) Values(key string) []string {
+	return slices.Clone(q.params[key])
 }
 
 func (q *URLQuery) Remove(keys ...string) *URLQuery {
@@ -91,23 +135,23 @@ func (q *URLQuery) String() string {
 	}
 
 	values := url.Values{}
-	for k, v := range q.params {
-		if v == "" {
-			continue
-		}
-		values.Set(k, v)
+	for k, v := range q.Params() {
+		values.Add(k, v)
 	}
 	return q.path + "?" + values.Encode()
 }
 
+// Params iterates over all the non-blank key values. Keys with multiple values are returned multiple times.
 func (q *URLQuery) Params() iter.Seq2[string, string] {
 	return func(yield func(string, string) bool) {
-		for k, v := range q.params {
-			if v == "" {
-				continue
-			}
-			if !yield(k, v) {
-				return
+		for k, values := range q.params {
+			for _, v := range values {
+				if v == "" {
+					continue
+				}
+				if !yield(k, v) {
+					return
+				}
 			}
 		}
 	}